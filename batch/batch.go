@@ -0,0 +1,245 @@
+// Package batch registers the webhooks described in a JSON lines file and
+// follows them together until they finish. Each line is an object as
+//
+//	{"url": "https://your-url.com", "body": {"foo": "bar"}, "delay": "1h", "headers": {"X-Request-ID": "42"}}
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+// Line is a line of a batch file describing a webhook to register
+type Line struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+	// Body is sent as it is, but JSON strings are sent unquoted when the
+	// content type is not JSON
+	Body        json.RawMessage   `json:"body"`
+	ContentType string            `json:"contentType"`
+	Headers     map[string]string `json:"headers"`
+	Sec         *int              `json:"sec"`
+	Delay       string            `json:"delay"` // a duration, e.g. 2h30m
+	At          string            `json:"at"`    // a date, in the location given when it has no offset
+}
+
+// Webhook is a webhook of a batch file to register
+type Webhook struct {
+	Line    int // number of the line describing it, starting at 1
+	Request *timehook.RegisterRequest
+}
+
+// Result is the result of a webhook of a batch file
+type Result struct {
+	Line    int
+	ID      string
+	Outcome timehook.Outcome
+	Status  timehook.Status
+	Err     error
+}
+
+// OutcomeSkipped is the outcome of the webhooks not registered after an
+// error
+const OutcomeSkipped timehook.Outcome = "skipped"
+
+// ErrEmpty is returned reading a batch file without webhooks
+var ErrEmpty = errors.New("no webhooks")
+
+// LineError is returned reading a wrong line of a batch file
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string { return fmt.Sprintf("line %d: %s", e.Line, e.Err) }
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// Read reads the webhooks of a batch file. Blank lines are skipped. Any
+// wrong line is a *LineError, so nothing is registered. Dates without
+// offset are in loc.
+func Read(r io.Reader, loc *time.Location) ([]Webhook, error) {
+	var webhooks []Webhook
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for n := 1; s.Scan(); n++ {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		req, err := ParseLine(s.Bytes(), loc)
+		if err != nil {
+			return nil, &LineError{Line: n, Err: err}
+		}
+		webhooks = append(webhooks, Webhook{Line: n, Request: req})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, ErrEmpty
+	}
+	return webhooks, nil
+}
+
+// ParseLine parses a line of a batch file into the request to register its
+// webhook. Dates without offset are in loc.
+func ParseLine(b []byte, loc *time.Location) (*timehook.RegisterRequest, error) {
+	var l Line
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&l); err != nil {
+		return nil, err
+	}
+	if l.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	r := &timehook.RegisterRequest{
+		URL:         l.URL,
+		Method:      strings.ToUpper(l.Method),
+		ContentType: l.ContentType,
+	}
+	switch r.Method {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return nil, fmt.Errorf("unsupported method %q", l.Method)
+	}
+	if len(l.Headers) > 0 {
+		r.Headers = http.Header{}
+		for name, v := range l.Headers {
+			r.Headers.Set(name, v)
+		}
+	}
+
+	body := []byte(l.Body)
+	if len(body) > 0 && body[0] == '"' && r.ContentType != "" && !timehook.IsJSON(r.ContentType) {
+		var text string
+		if err := json.Unmarshal(body, &text); err != nil {
+			return nil, fmt.Errorf("wrong body: %s", err)
+		}
+		body = []byte(text)
+	}
+	if len(body) > 0 {
+		r.Body = bytes.NewReader(body)
+	}
+
+	return r, l.schedule(r, loc)
+}
+
+// schedule sets when the webhook of the line is sent, after 5 seconds when
+// the line does not say it as the --sec flag does
+func (l *Line) schedule(r *timehook.RegisterRequest, loc *time.Location) error {
+	n := 0
+	for _, set := range []bool{l.Sec != nil, l.Delay != "", l.At != ""} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("only one of sec, delay and at can be used")
+	}
+
+	switch {
+	case l.At != "":
+		at, err := timehook.ParseTimeIn(l.At, loc)
+		if err != nil {
+			return err
+		}
+		if !at.After(time.Now()) {
+			return fmt.Errorf("at %s is in the past", at.Format(time.RFC3339))
+		}
+		r.At = at
+	case l.Delay != "":
+		d, err := time.ParseDuration(l.Delay)
+		if err != nil || d < 0 {
+			return fmt.Errorf("wrong delay %q", l.Delay)
+		}
+		r.Delay = d
+	case l.Sec != nil:
+		if *l.Sec < 0 {
+			return fmt.Errorf("sec %d is negative", *l.Sec)
+		}
+		r.Delay = time.Duration(*l.Sec) * time.Second
+	default:
+		r.Delay = 5 * time.Second
+	}
+	return nil
+}
+
+// Register registers the webhooks, at most concurrency at once, and returns
+// the result of each one. Unless continueOnError, the webhooks not
+// registered yet are skipped after an error.
+func Register(ctx context.Context, api timehook.API, webhooks []Webhook, concurrency int, continueOnError bool) []Result {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	results := make([]Result, len(webhooks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, w := range webhooks {
+		results[i].Line = w.Line
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Outcome = OutcomeSkipped
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *timehook.RegisterRequest, res *Result) {
+			defer func() { <-sem; wg.Done() }()
+			rr, err := api.Register(ctx, r)
+			if err != nil {
+				res.Outcome, res.Err = timehook.OutcomeError, err
+				if ctx.Err() != nil {
+					res.Outcome = timehook.OutcomeCancelled
+				} else if !continueOnError {
+					stop()
+				}
+				return
+			}
+			res.ID = rr.ID
+		}(w.Request, &results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// Watch polls the webhooks registered together every interval until they
+// finish and sets their results
+func Watch(ctx context.Context, client *timehook.Client, results []Result, interval time.Duration) {
+	var IDs []string
+	for _, r := range results {
+		if r.ID != "" {
+			IDs = append(IDs, r.ID)
+		}
+	}
+
+	g := client.WatchGroup(ctx, IDs, interval)
+	for range g.C {
+	}
+	res := g.Result()
+	for i := range results {
+		r, ok := res.Result(results[i].ID)
+		if !ok {
+			continue
+		}
+		results[i].Outcome, results[i].Err = r.Outcome, res.Errors[r.WebhookID]
+		if r.State != nil {
+			results[i].Status = r.State.Status
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/batch"
+	"github.com/timehook/cli-client/mock"
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestParseLine(t *testing.T) {
+	madrid := time.FixedZone("CET", 3600)
+	tt := []struct {
+		name            string
+		given           string
+		wantDelay       time.Duration
+		wantAt          string
+		wantMethod      string
+		wantBody        string
+		wantContentType string
+		wantHeader      http.Header
+	}{
+		{name: "defaults", given: `{"url": "https://your-url.com"}`, wantDelay: 5 * time.Second},
+		{name: "sec", given: `{"url": "https://your-url.com", "sec": 30}`, wantDelay: 30 * time.Second},
+		{name: "zero sec", given: `{"url": "https://your-url.com", "sec": 0}`},
+		{name: "delay", given: `{"url": "https://your-url.com", "delay": "1h30m"}`, wantDelay: 90 * time.Minute},
+		{name: "at", given: `{"url": "https://your-url.com", "at": "2099-11-01T09:00:00+02:00"}`, wantAt: "2099-11-01T07:00:00Z"},
+		{name: "at in the location", given: `{"url": "https://your-url.com", "at": "2099-11-01 09:00"}`, wantAt: "2099-11-01T08:00:00Z"},
+		{
+			name:       "method and JSON body",
+			given:      `{"url": "https://your-url.com", "method": "put", "body": {"foo": "bar"}}`,
+			wantDelay:  5 * time.Second,
+			wantMethod: http.MethodPut,
+			wantBody:   `{"foo": "bar"}`,
+		},
+		{
+			name:            "text body",
+			given:           `{"url": "https://your-url.com", "contentType": "text/plain", "body": "hello"}`,
+			wantDelay:       5 * time.Second,
+			wantBody:        "hello",
+			wantContentType: "text/plain",
+		},
+		{
+			name:            "JSON string body",
+			given:           `{"url": "https://your-url.com", "contentType": "application/json", "body": "hello"}`,
+			wantDelay:       5 * time.Second,
+			wantBody:        `"hello"`,
+			wantContentType: "application/json",
+		},
+		{
+			name:       "headers",
+			given:      `{"url": "https://your-url.com", "headers": {"x-request-id": "42"}}`,
+			wantDelay:  5 * time.Second,
+			wantHeader: http.Header{"X-Request-Id": {"42"}},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			// when
+			r, err := batch.ParseLine([]byte(v.given), madrid)
+
+			// then
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if r.URL != "https://your-url.com" {
+				t.Errorf("wrong URL want %s got %s", "https://your-url.com", r.URL)
+			}
+			if r.Delay != v.wantDelay {
+				t.Errorf("wrong delay want %s got %s", v.wantDelay, r.Delay)
+			}
+			if at := r.At.UTC().Format(time.RFC3339); v.wantAt != "" && at != v.wantAt {
+				t.Errorf("wrong at want %s got %s", v.wantAt, at)
+			}
+			if v.wantAt == "" && !r.At.IsZero() {
+				t.Errorf("unexpected at %s", r.At)
+			}
+			if r.Method != v.wantMethod {
+				t.Errorf("wrong method want %q got %q", v.wantMethod, r.Method)
+			}
+			if r.ContentType != v.wantContentType {
+				t.Errorf("wrong content type want %q got %q", v.wantContentType, r.ContentType)
+			}
+			var body []byte
+			if r.Body != nil {
+				body, _ = ioutil.ReadAll(r.Body)
+			}
+			if string(body) != v.wantBody {
+				t.Errorf("wrong body want %q got %q", v.wantBody, body)
+			}
+			for name := range v.wantHeader {
+				if r.Headers.Get(name) != v.wantHeader.Get(name) {
+					t.Errorf("wrong header %s want %q got %q", name, v.wantHeader.Get(name), r.Headers.Get(name))
+				}
+			}
+		})
+	}
+}
+
+func TestParseLine_Error(t *testing.T) {
+	tt := []struct {
+		given string
+		want  string
+	}{
+		{given: `{"method": "POST"}`, want: "url is required"},
+		{given: `{"url": "https://your-url.com", "foo": "bar"}`, want: `unknown field "foo"`},
+		{given: `{"url": "https://your-url.com", "method": "TRACE"}`, want: `unsupported method "TRACE"`},
+		{given: `{"url": "https://your-url.com", "sec": 5, "delay": "5s"}`, want: "only one of sec, delay and at"},
+		{given: `{"url": "https://your-url.com", "sec": -1}`, want: "sec -1 is negative"},
+		{given: `{"url": "https://your-url.com", "delay": "soon"}`, want: `wrong delay "soon"`},
+		{given: `{"url": "https://your-url.com", "at": "2001-01-01T00:00:00Z"}`, want: "is in the past"},
+		{given: `{"url": "https://your-url.com", "at": "tomorrow"}`, want: `can not parse date "tomorrow"`},
+		{given: `{"url": `, want: "unexpected EOF"},
+	}
+
+	for _, v := range tt {
+		t.Run(v.given, func(t *testing.T) {
+			_, err := batch.ParseLine([]byte(v.given), time.UTC)
+			if err == nil || !strings.Contains(err.Error(), v.want) {
+				t.Errorf("error containing %q expected, got %v", v.want, err)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	// given
+	file := `{"url": "https://your-url.com/1"}
+
+{"url": "https://your-url.com/3", "delay": "1h"}
+`
+
+	// when
+	webhooks, err := batch.Read(strings.NewReader(file), time.UTC)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(webhooks) != 2 {
+		t.Fatalf("wrong number of webhooks want %d got %d", 2, len(webhooks))
+	}
+	for i, want := range []struct {
+		line int
+		url  string
+	}{{1, "https://your-url.com/1"}, {3, "https://your-url.com/3"}} {
+		if webhooks[i].Line != want.line || webhooks[i].Request.URL != want.url {
+			t.Errorf("wrong webhook %d want line %d %s got line %d %s", i, want.line, want.url, webhooks[i].Line, webhooks[i].Request.URL)
+		}
+	}
+}
+
+func TestRead_Error(t *testing.T) {
+	tt := []struct {
+		name     string
+		given    string
+		wantLine int
+		wantErr  error
+	}{
+		{name: "wrong line", given: "{\"url\": \"https://your-url.com\"}\n\n\n{\"sec\": 5}\n", wantLine: 4},
+		{name: "empty", given: "\n  \n", wantErr: batch.ErrEmpty},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			_, err := batch.Read(strings.NewReader(v.given), time.UTC)
+
+			if v.wantErr != nil {
+				if !errors.Is(err, v.wantErr) {
+					t.Errorf("error expected %v got %v", v.wantErr, err)
+				}
+				return
+			}
+			var lineErr *batch.LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("*LineError expected got %v", err)
+			}
+			if lineErr.Line != v.wantLine {
+				t.Errorf("wrong line want %d got %d", v.wantLine, lineErr.Line)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	tt := []struct {
+		name            string
+		responses       []interface{}
+		concurrency     int
+		continueOnError bool
+		want            []timehook.Outcome
+	}{
+		{
+			name:        "all registered",
+			responses:   []interface{}{mock.RegisteredSuccess(), mock.RegisteredSuccess(), mock.RegisteredSuccess(), mock.RegisteredSuccess()},
+			concurrency: 3,
+			want:        []timehook.Outcome{"", "", "", ""},
+		},
+		{
+			name:        "stop on error",
+			responses:   []interface{}{mock.RegisteredSuccess(), mock.InternalServerError()},
+			concurrency: 1,
+			want:        []timehook.Outcome{"", timehook.OutcomeError, batch.OutcomeSkipped, batch.OutcomeSkipped},
+		},
+		{
+			name:            "continue on error",
+			responses:       []interface{}{mock.RegisteredSuccess(), mock.InternalServerError(), mock.RegisteredSuccess(), mock.RegisteredSuccess()},
+			concurrency:     1,
+			continueOnError: true,
+			want:            []timehook.Outcome{"", timehook.OutcomeError, "", ""},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			// given
+			HTTPClient := mock.HTTPClient(v.responses)
+			client := timehook.New("api-key", HTTPClient)
+			var webhooks []batch.Webhook
+			for i := range v.want {
+				webhooks = append(webhooks, batch.Webhook{
+					Line:    i + 1,
+					Request: &timehook.RegisterRequest{URL: "https://your-url.com", Delay: time.Second},
+				})
+			}
+
+			// when
+			results := batch.Register(context.Background(), client, webhooks, v.concurrency, v.continueOnError)
+
+			// then
+			if len(results) != len(v.want) {
+				t.Fatalf("wrong number of results want %d got %d", len(v.want), len(results))
+			}
+			for i, r := range results {
+				if r.Line != i+1 {
+					t.Errorf("wrong line of result %d want %d got %d", i, i+1, r.Line)
+				}
+				if r.Outcome != v.want[i] {
+					t.Errorf("wrong outcome of line %d want %q got %q", r.Line, v.want[i], r.Outcome)
+				}
+				if registered := r.Outcome == ""; registered != (r.ID != "") {
+					t.Errorf("wrong ID of line %d: %q", r.Line, r.ID)
+				}
+				if (r.Outcome == timehook.OutcomeError) != (r.Err != nil) {
+					t.Errorf("wrong error of line %d: %v", r.Line, r.Err)
+				}
+			}
+			if len(HTTPClient.Spies()) != len(v.responses) {
+				t.Errorf("wrong number of requests want %d got %d", len(v.responses), len(HTTPClient.Spies()))
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.StateSucceeded(), mock.StateFailed()})
+	client := timehook.New("api-key", HTTPClient)
+	results := []batch.Result{
+		{Line: 1, ID: "first"},
+		{Line: 2, Outcome: timehook.OutcomeError, Err: timehook.ErrUnauthorized},
+		{Line: 3, ID: "second"},
+		{Line: 4, Outcome: batch.OutcomeSkipped},
+	}
+
+	// when
+	batch.Watch(context.Background(), client, results, 1*time.Nanosecond)
+
+	// then
+	want := []struct {
+		outcome timehook.Outcome
+		status  timehook.Status
+	}{
+		{timehook.OutcomeSucceeded, timehook.StatusSucceeded},
+		{timehook.OutcomeError, ""},
+		{timehook.OutcomeFailed, timehook.StatusFailed},
+		{batch.OutcomeSkipped, ""},
+	}
+	for i, r := range results {
+		if r.Outcome != want[i].outcome || r.Status != want[i].status {
+			t.Errorf("wrong result of line %d want %s %s got %s %s", r.Line, want[i].outcome, want[i].status, r.Outcome, r.Status)
+		}
+	}
+	if results[1].Err != timehook.ErrUnauthorized {
+		t.Errorf("error of line %d changed: %v", 2, results[1].Err)
+	}
+}
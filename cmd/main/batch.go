@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/timehook/cli-client/batch"
+	"github.com/timehook/cli-client/timehook"
+)
+
+// batchResult is the result of a line of a batch file to print it
+type batchResult struct {
+	Line    int              `json:"line"`
+	ID      string           `json:"id,omitempty"`
+	Outcome timehook.Outcome `json:"outcome"`
+	Status  timehook.Status  `json:"status,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+func registerBatch(ctx context.Context, args []string) int {
+	var opts options
+	fs := newFlagSet("batch", "<file>", "Registers the webhooks described in a JSON lines file, - for the standard input, and polls them until they finish.\n"+
+		`Each line is an object with url, method, body, contentType, headers, and sec, delay or at, e.g.
+  {"url": "https://your-url.com", "body": {"foo": "bar"}, "delay": "1h", "headers": {"X-Request-ID": "42"}}`)
+	opts.flags(fs)
+	concurrency := fs.Int("concurrency", 4, "maximum number of webhooks registered at once")
+	continueOnError := fs.Bool("continue-on-error", false, "keep registering the next lines when one fails, instead of stopping")
+	interval := fs.Duration("interval", defaultInterval(), "interval between state queries of each webhook")
+	tz := fs.String("tz", "Local", "time zone of the dates without offset, e.g. Europe/Madrid")
+	parse(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *concurrency <= 0 {
+		return fail(usagef("--concurrency %d must be positive", *concurrency))
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return fail(usagef("unknown time zone %q", *tz))
+	}
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	webhooks, err := readBatch(fs.Arg(0), loc)
+	if err != nil {
+		return fail(err)
+	}
+
+	results := batch.Register(ctx, client, webhooks, *concurrency, *continueOnError)
+	batch.Watch(ctx, client, results, *interval)
+
+	printBatch(opts.output, results)
+	for _, r := range results {
+		if r.Outcome != timehook.OutcomeSucceeded {
+			return outcomeCode(r.Outcome, r.Err)
+		}
+	}
+	return exitSucceeded
+}
+
+// newBatchResult returns the result of a line to print it
+func newBatchResult(r batch.Result) batchResult {
+	doc := batchResult{Line: r.Line, ID: r.ID, Outcome: r.Outcome, Status: r.Status}
+	if r.Err != nil {
+		doc.Error = r.Err.Error()
+	}
+	return doc
+}
+
+// printBatch prints the results of the lines and a summary of their
+// outcomes
+func printBatch(f format, results []batch.Result) {
+	counts := map[timehook.Outcome]int{}
+	for _, r := range results {
+		counts[r.Outcome]++
+	}
+
+	switch f {
+	case formatJSON:
+		docs := make([]batchResult, len(results))
+		for i, r := range results {
+			docs[i] = newBatchResult(r)
+		}
+		printJSON(f, struct {
+			Results []batchResult            `json:"results"`
+			Summary map[timehook.Outcome]int `json:"summary"`
+		}{docs, counts})
+		return
+	case formatJSONL:
+		for _, r := range results {
+			printJSON(f, newBatchResult(r))
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE\tID\tOUTCOME\tSTATUS\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.Line, r.ID, r.Outcome, r.Status, newBatchResult(r).Error)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%s\n", summary(counts))
+}
+
+// readBatch reads the webhooks of the batch file at path, - for the
+// standard input. Any wrong line is a usage error, so nothing is registered.
+func readBatch(path string, loc *time.Location) ([]batch.Webhook, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	webhooks, err := batch.Read(in, loc)
+	var lineErr *batch.LineError
+	switch {
+	case errors.As(err, &lineErr):
+		return nil, usageError{err}
+	case errors.Is(err, batch.ErrEmpty):
+		return nil, usagef("no webhooks in %s", path)
+	case err != nil:
+		return nil, fmt.Errorf("can not read %s: %s", path, err)
+	}
+	return webhooks, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func cancel(ctx context.Context, args []string) int {
+	var opts options
+	fs := newFlagSet("cancel", "<id>", "Cancels the webhook identified by id so it is not sent.")
+	opts.flags(fs)
+	parse(fs, args)
+	ID, ok := webhookID(fs)
+	if !ok {
+		return exitUsage
+	}
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := client.Cancel(ctx, ID); err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("webhook %s cancelled\n", ID)
+	return exitSucceeded
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/timehook/cli-client/config"
+)
+
+// profile is the configuration profile selected. It is loaded before the
+// flags are parsed, as its settings are their defaults.
+var profile = &config.Profile{Headers: make(map[string]string)}
+
+// loadProfile loads the profile selected with --profile in args, or with
+// the TIMEHOOK_PROFILE environment variable, from the configuration file.
+// Without configuration directory, as in CI, the profile is empty unless
+// one is selected.
+func loadProfile(args []string) (*config.Profile, error) {
+	name := profileName(args)
+	path, err := config.DefaultPath()
+	if err != nil {
+		if name == "" {
+			return &config.Profile{Headers: make(map[string]string)}, nil
+		}
+		return nil, err
+	}
+	c, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Profile(name)
+}
+
+// profileName returns the profile given with --profile in args, or with the
+// TIMEHOOK_PROFILE environment variable
+func profileName(args []string) string {
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		name := strings.TrimLeft(a, "-")
+		if name == "profile" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "profile=") {
+			return strings.TrimPrefix(name, "profile=")
+		}
+	}
+	return os.Getenv("TIMEHOOK_PROFILE")
+}
+
+// profileFlag registers --profile, already parsed by profileName, to show
+// it in the usage of the command
+func profileFlag(fs *flag.FlagSet) {
+	fs.String("profile", os.Getenv("TIMEHOOK_PROFILE"), "configuration profile, defaults to TIMEHOOK_PROFILE environment variable or the default profile of the file")
+}
+
+// defaultInterval returns the interval between state queries of the profile
+// or the default one
+func defaultInterval() time.Duration {
+	if profile.Interval > 0 {
+		return profile.Interval
+	}
+	return 1 * time.Second
+}
+
+func configure(ctx context.Context, args []string) int {
+	fs := newFlagSet("config", "[view | path | set <setting> <value> | use <profile> | edit]",
+		"Views or edits the configuration file, with profiles of settings: "+strings.Join(config.Settings, ", ")+".\n"+
+			"Settings are taken from the flags first, then the environment variables, then the profile and then the defaults.\n"+
+			"A value - is read from the standard input, to keep the API key out of the shell history as in: config set key -")
+	name := fs.String("profile", os.Getenv("TIMEHOOK_PROFILE"), "profile to set, defaults to TIMEHOOK_PROFILE environment variable or the default profile of the file")
+	showKeys := fs.Bool("show-keys", false, "show the API keys viewing the configuration")
+	parse(fs, args)
+	sub, params := fs.Arg(0), fs.Args()
+	if len(params) > 0 {
+		params = params[1:]
+	}
+
+	path, err := config.DefaultPath()
+	if err != nil {
+		return fail(err)
+	}
+	if sub == "path" {
+		fmt.Println(path)
+		return exitSucceeded
+	}
+	if sub == "edit" {
+		return edit(path)
+	}
+
+	c, err := config.Load(path)
+	if err != nil {
+		return fail(err)
+	}
+	switch {
+	case (sub == "" || sub == "view") && len(params) == 0:
+		if len(c.Profiles) == 0 {
+			fmt.Printf("# no profiles in %s\n", path)
+			return exitSucceeded
+		}
+		if !*showKeys {
+			for _, p := range c.Profiles {
+				p.Key = mask(p.Key)
+			}
+		}
+		c.Write(os.Stdout)
+		return exitSucceeded
+	case sub == "set" && len(params) == 2:
+		value := params[1]
+		if value == "-" {
+			if isTerminal(os.Stdin) {
+				fmt.Fprintf(os.Stderr, "%s: ", params[0])
+			}
+			if value, err = readValue(os.Stdin); err != nil {
+				return fail(err)
+			}
+		}
+		if err := c.Set(*name, params[0], value); err != nil {
+			return fail(usageError{err})
+		}
+	case sub == "use" && len(params) == 1:
+		if _, ok := c.Profiles[params[0]]; !ok {
+			return fail(usagef("unknown profile %q", params[0]))
+		}
+		c.Default = params[0]
+	default:
+		fs.Usage()
+		return exitUsage
+	}
+
+	if err := c.Save(path); err != nil {
+		return fail(err)
+	}
+	return exitSucceeded
+}
+
+// edit opens the configuration file at path with the editor of the user
+// and checks it once edited
+func edit(path string) int {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fail(err)
+	}
+
+	cmd := exec.Command(editor, path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fail(fmt.Errorf("can not run editor %s: %s", editor, err))
+	}
+	if _, err := config.Load(path); err != nil {
+		return fail(err)
+	}
+	return exitSucceeded
+}
+
+// readValue reads a setting from the first line of r
+func readValue(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	value := strings.TrimSpace(line)
+	if value == "" {
+		return "", usagef("no value given on the standard input")
+	}
+	return value, nil
+}
+
+// mask hides the API key but its last characters
+func mask(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
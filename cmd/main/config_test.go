@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadValue(t *testing.T) {
+	tt := []struct {
+		name    string
+		given   string
+		want    string
+		wantErr bool
+	}{
+		{name: "line", given: "the-key\n", want: "the-key"},
+		{name: "without newline", given: "the-key", want: "the-key"},
+		{name: "first line", given: "the-key\r\nmetadata\n", want: "the-key"},
+		{name: "empty", given: "", wantErr: true},
+		{name: "blank line", given: " \n", wantErr: true},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			// when
+			got, err := readValue(strings.NewReader(v.given))
+
+			// then
+			if v.wantErr {
+				if err == nil {
+					t.Errorf("error expected, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != v.want {
+				t.Errorf("wrong value want %q got %q", v.want, got)
+			}
+		})
+	}
+}
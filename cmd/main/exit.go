@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+// Exit codes of the CLI, documented in the README
+const (
+	exitSucceeded    = 0  // the webhook succeeded or the command finished fine
+	exitError        = 1  // any error not covered below, e.g. configuration
+	exitUsage        = 2  // wrong flags or arguments
+	exitFailed       = 3  // the webhook failed
+	exitTimeout      = 4  // the webhook timed out
+	exitUnknown      = 5  // the webhook got an unexpected status
+	exitUnauthorized = 6  // the API rejected the API key
+	exitAPIError     = 7  // the API responded with an unexpected status code
+	exitNetworkError = 8  // the API could not be reached
+	exitCancelled    = 9  // the command was interrupted
+	exitMismatch     = 10 // the webhook received was not the one expected
+)
+
+// usageError is an error in the flags or arguments given to a command
+type usageError struct{ error }
+
+// usagef returns a usageError formatted with fmt.Errorf
+func usagef(format string, a ...interface{}) error {
+	return usageError{fmt.Errorf(format, a...)}
+}
+
+// outcomeCode returns the exit code of the result of a process
+func outcomeCode(outcome timehook.Outcome, err error) int {
+	switch outcome {
+	case timehook.OutcomeSucceeded:
+		return exitSucceeded
+	case timehook.OutcomeFailed:
+		return exitFailed
+	case timehook.OutcomeTimeout:
+		return exitTimeout
+	case timehook.OutcomeUnknown:
+		return exitUnknown
+	case timehook.OutcomeCancelled:
+		return exitCancelled
+	}
+	return errorCode(err)
+}
+
+// errorCode returns the exit code of the error which finished a command
+func errorCode(err error) int {
+	var apiErr *timehook.APIError
+	var netErr *timehook.NetworkError
+	var usageErr usageError
+	switch {
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return exitCancelled
+	case errors.Is(err, timehook.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.As(err, &apiErr):
+		return exitAPIError
+	case errors.As(err, &netErr):
+		return exitNetworkError
+	}
+	return exitError
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestOutcomeCode(t *testing.T) {
+	unauthorized := &timehook.APIError{StatusCode: 401, Status: "401 Unauthorized"}
+	tt := []struct {
+		name    string
+		outcome timehook.Outcome
+		err     error
+		want    int
+	}{
+		{name: "succeeded", outcome: timehook.OutcomeSucceeded, want: exitSucceeded},
+		{name: "failed", outcome: timehook.OutcomeFailed, want: exitFailed},
+		{name: "timeout", outcome: timehook.OutcomeTimeout, want: exitTimeout},
+		{name: "unknown", outcome: timehook.OutcomeUnknown, want: exitUnknown},
+		{name: "cancelled", outcome: timehook.OutcomeCancelled, err: context.Canceled, want: exitCancelled},
+		{name: "error", outcome: timehook.OutcomeError, err: unauthorized, want: exitUnauthorized},
+		{name: "skipped", outcome: "skipped", err: errors.New("not registered"), want: exitError},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			if got := outcomeCode(v.outcome, v.err); got != v.want {
+				t.Errorf("wrong exit code want %d got %d", v.want, got)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	unauthorized := &timehook.APIError{StatusCode: 401, Status: "401 Unauthorized"}
+	serverError := &timehook.APIError{StatusCode: 500, Status: "500 Internal Server Error"}
+	tt := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "usage", err: usagef("unknown time zone %q", "Mars"), want: exitUsage},
+		{name: "wrapped usage", err: fmt.Errorf("line 2: %w", usagef("url is required")), want: exitUsage},
+		{name: "cancelled", err: context.Canceled, want: exitCancelled},
+		{name: "deadline", err: context.DeadlineExceeded, want: exitCancelled},
+		{name: "network cancelled", err: &timehook.NetworkError{Op: "execute request", Err: context.Canceled}, want: exitCancelled},
+		{name: "unauthorized", err: unauthorized, want: exitUnauthorized},
+		{name: "wrapped unauthorized", err: fmt.Errorf("occurrence 2: %w", unauthorized), want: exitUnauthorized},
+		{name: "api error", err: serverError, want: exitAPIError},
+		{name: "wrapped api error", err: fmt.Errorf("occurrence 2: %w", serverError), want: exitAPIError},
+		{name: "network", err: &timehook.NetworkError{Op: "execute request", Err: errors.New("connection refused")}, want: exitNetworkError},
+		{name: "other", err: errors.New("TIMEHOOK_KEY environment variable not defined"), want: exitError},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			if got := errorCode(v.err); got != v.want {
+				t.Errorf("wrong exit code want %d got %d", v.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func list(ctx context.Context, args []string) int {
+	var opts options
+	fs := newFlagSet("list", "", "Lists the webhooks registered with the API key.")
+	opts.flags(fs)
+	parse(fs, args)
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	srs, err := client.List(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	switch opts.output {
+	case formatJSON:
+		printJSON(opts.output, srs)
+		return exitSucceeded
+	case formatJSONL:
+		for _, sr := range srs {
+			printJSON(opts.output, sr)
+		}
+		return exitSucceeded
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tSCHEDULED AT")
+	for _, sr := range srs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", sr.ID, sr.Status, sr.ScheduledAt)
+	}
+	w.Flush()
+
+	return exitSucceeded
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/timehook/cli-client/batch"
+	"github.com/timehook/cli-client/timehook"
+)
+
+// format is the output format of the commands, set with --output
+type format string
+
+const (
+	formatText  format = "text"
+	formatJSON  format = "json"
+	formatJSONL format = "jsonl"
+)
+
+func (f *format) String() string { return string(*f) }
+
+func (f *format) Set(v string) error {
+	switch format(v) {
+	case formatText, formatJSON, formatJSONL:
+		*f = format(v)
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use text, json or jsonl", v)
+}
+
+// progress is how the text format shows the progress of webhooks, set with
+// --progress
+type progress string
+
+const (
+	progressAuto  progress = "auto"  // tty on a terminal, plain otherwise
+	progressTTY   progress = "tty"   // a line redrawn with a spinner
+	progressPlain progress = "plain" // a line per step with a timestamp
+	progressQuiet progress = "quiet" // only the final result
+)
+
+func (p *progress) String() string { return string(*p) }
+
+func (p *progress) Set(v string) error {
+	switch progress(v) {
+	case progressAuto, progressTTY, progressPlain, progressQuiet:
+		*p = progress(v)
+		return nil
+	}
+	return fmt.Errorf("unknown progress %q, use auto, tty, plain or quiet", v)
+}
+
+// renderer returns the renderer showing the progress on stdout. Many
+// webhooks are shown plain on a terminal as well, as a single line can not
+// show them.
+func (p progress) renderer(many bool) timehook.Renderer {
+	switch {
+	case p == progressQuiet:
+		return timehook.NewQuietRenderer(os.Stdout)
+	case p == progressTTY && !many, p == progressAuto && !many && isTerminal(os.Stdout):
+		return timehook.NewTTYRenderer(os.Stdout)
+	}
+	return timehook.NewLogRenderer(os.Stdout)
+}
+
+// result is the JSON document of the final result of a process
+type result struct {
+	ID      string           `json:"id"`
+	Outcome timehook.Outcome `json:"outcome"`
+	*timehook.StateResponse
+	Error string `json:"error,omitempty"`
+}
+
+func newResult(res timehook.Result, err error) result {
+	r := result{StateResponse: res.State, ID: res.WebhookID, Outcome: res.Outcome}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// event is the JSON document of an event of a process
+type event struct {
+	Kind       timehook.EventKind `json:"kind"`
+	ID         string             `json:"id,omitempty"`
+	Status     timehook.Status    `json:"status,omitempty"`
+	Time       string             `json:"time,omitempty"`
+	ElapsedSec float64            `json:"elapsedSec"`
+	Error      string             `json:"error,omitempty"`
+}
+
+func newEvent(e timehook.Event) event {
+	ev := event{Kind: e.Kind, ID: e.WebhookID, ElapsedSec: e.Elapsed.Seconds()}
+	if e.State != nil {
+		ev.Status = e.State.Status
+	}
+	if !e.Time.IsZero() {
+		ev.Time = e.Time.Format(time.RFC3339)
+	}
+	if e.Err != nil {
+		ev.Error = e.Err.Error()
+	}
+	return ev
+}
+
+// summary returns the number of webhooks and how many finished with every
+// outcome, e.g. "3 webhooks: 2 succeeded, 1 failed"
+func summary(counts map[timehook.Outcome]int) string {
+	outcomes := []timehook.Outcome{
+		timehook.OutcomeSucceeded, timehook.OutcomeFailed, timehook.OutcomeTimeout, timehook.OutcomeUnknown,
+		timehook.OutcomeError, timehook.OutcomeCancelled, batch.OutcomeSkipped,
+	}
+	total := 0
+	var parts []string
+	for _, o := range outcomes {
+		if counts[o] > 0 {
+			total += counts[o]
+			parts = append(parts, fmt.Sprintf("%d %s", counts[o], o))
+		}
+	}
+	return fmt.Sprintf("%d webhooks: %s", total, strings.Join(parts, ", "))
+}
+
+// printJSON writes v as JSON on stdout, indented for the json format and in
+// a single line for the jsonl one
+func printJSON(f format, v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	if f == formatJSON {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(v)
+}
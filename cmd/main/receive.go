@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/timehook/cli-client/receiver"
+)
+
+// request is the JSON document of a request received
+type request struct {
+	At        string              `json:"at"`
+	WebhookID string              `json:"webhookId,omitempty"`
+	Method    string              `json:"method"`
+	URL       string              `json:"url"`
+	Header    map[string][]string `json:"header"`
+	Body      string              `json:"body"`
+}
+
+// check is the JSON document of the check of an expected webhook
+type check struct {
+	ID       string   `json:"id"`
+	Received bool     `json:"received"`
+	OK       bool     `json:"ok"`
+	Request  *request `json:"request,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func receive(ctx context.Context, args []string) int {
+	var opts options
+	fs := newFlagSet("receive", "", "Listens for webhooks, printing every request received, and checks the webhook expected arrives as expected.")
+	opts.flags(fs)
+	addr := fs.String("addr", "127.0.0.1:9000", "address to listen on")
+	status := fs.Int("status", http.StatusOK, "status code of the responses")
+	expectID := fs.String("expect-id", "", "ID of a webhook registered, wait for it and check it arrives within --tolerance of its scheduled time")
+	expectBody := fs.String("expect-body", "", "body expected of the webhook, JSON bodies are compared by value")
+	expectBodyFile := fs.String("expect-body-file", "", "file with the body expected of the webhook")
+	matchAny := fs.Bool("match-any", false, "take any request without X-Timehook-Id header as the webhook expected, otherwise they are matched by --expect-body")
+	tolerance := fs.Duration("tolerance", 5*time.Second, "time the webhook may arrive before or after it is scheduled")
+	parse(fs, args)
+	if (isSet(fs, "expect-body") || *expectBodyFile != "") && *expectID == "" {
+		return fail(usagef("--expect-body and --expect-body-file need --expect-id"))
+	}
+	if *matchAny && *expectID == "" {
+		return fail(usagef("--match-any needs --expect-id"))
+	}
+	if isSet(fs, "expect-body") && *expectBodyFile != "" {
+		return fail(usagef("--expect-body and --expect-body-file can not be used together"))
+	}
+
+	expected := receiver.Expectation{ID: *expectID, AnyRequest: *matchAny}
+	if isSet(fs, "expect-body") {
+		expected.Body = []byte(*expectBody)
+	}
+	if *expectBodyFile != "" {
+		b, err := ioutil.ReadFile(*expectBodyFile)
+		if err != nil {
+			return fail(err)
+		}
+		expected.Body = b
+	}
+
+	var mu sync.Mutex // serializes the output
+	var received []request
+	rec := receiver.New(receiver.WithStatus(*status), receiver.WithNotify(func(r receiver.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		doc := newRequest(r)
+		received = append(received, doc)
+		switch opts.output {
+		case formatText:
+			printRequest(doc)
+		case formatJSONL:
+			printJSON(opts.output, doc)
+		}
+	}))
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fail(err)
+	}
+	srv := &http.Server{Handler: rec}
+	go srv.Serve(ln)
+	defer srv.Close()
+	fmt.Fprintf(os.Stderr, "listening on http://%s\n", ln.Addr())
+
+	if *expectID == "" {
+		<-ctx.Done()
+		if opts.output == formatJSON {
+			mu.Lock()
+			printJSON(opts.output, received)
+			mu.Unlock()
+		}
+		return exitSucceeded
+	}
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	sr, err := client.State(ctx, *expectID)
+	if err != nil {
+		return fail(err)
+	}
+	if expected.ScheduledAt = sr.ScheduledTime(); expected.ScheduledAt.IsZero() {
+		return fail(fmt.Errorf("webhook %s without scheduled date", *expectID))
+	}
+	expected.Tolerance = *tolerance
+
+	waitCtx, cancel := context.WithDeadline(ctx, expected.Deadline())
+	defer cancel()
+	r, err := rec.Wait(waitCtx, expected.Match)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c := check{ID: *expectID}
+	switch {
+	case ctx.Err() != nil:
+		return fail(ctx.Err())
+	case err != nil:
+		c.Error = fmt.Sprintf("not received by %s", expected.Deadline().Format(time.RFC3339))
+		for _, r := range received {
+			if r.WebhookID == "" && !expected.AnyRequest {
+				c.Error += ", use --match-any to take the requests without " + receiver.IDHeader + " header as the webhook"
+				break
+			}
+		}
+	default:
+		doc := newRequest(r)
+		c.Received, c.Request = true, &doc
+		if err := expected.Check(r); err != nil {
+			c.Error = err.Error()
+		}
+	}
+	c.OK = c.Error == ""
+
+	switch {
+	case opts.output != formatText:
+		printJSON(opts.output, c)
+	case c.OK:
+		fmt.Printf("[OK] webhook %s received %s from its scheduled time\n", c.ID, r.At.Sub(expected.ScheduledAt).Round(time.Millisecond))
+	default:
+		fmt.Printf("[Mismatch] webhook %s %s\n", c.ID, c.Error)
+	}
+	if !c.OK {
+		return exitMismatch
+	}
+	return exitSucceeded
+}
+
+func newRequest(r receiver.Request) request {
+	return request{
+		At:        r.At.Format(time.RFC3339Nano),
+		WebhookID: r.WebhookID,
+		Method:    r.Method,
+		URL:       r.URL,
+		Header:    r.Header,
+		Body:      string(r.Body),
+	}
+}
+
+// printRequest prints the request as text, with its headers sorted
+func printRequest(r request) {
+	fmt.Printf("[%s] %s %s", r.At, r.Method, r.URL)
+	if r.WebhookID != "" {
+		fmt.Printf(" webhook %s", r.WebhookID)
+	}
+	fmt.Println()
+
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range r.Header[name] {
+			fmt.Printf("  %s: %s\n", name, v)
+		}
+	}
+	if r.Body != "" {
+		fmt.Printf("\n  %s\n", r.Body)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func register(ctx context.Context, args []string) int {
+	var opts options
+	var webhook webhookFlags
+	fs := newFlagSet("register", "", "Registers a webhook and prints its ID without waiting for it.")
+	opts.flags(fs)
+	webhook.flags(fs)
+	parse(fs, args)
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	r, release, err := webhook.request(fs)
+	if err != nil {
+		return fail(err)
+	}
+	defer release()
+
+	rr, err := client.Register(ctx, r)
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.output == formatText {
+		fmt.Println(rr.ID)
+	} else {
+		printJSON(opts.output, rr)
+	}
+	return exitSucceeded
+}
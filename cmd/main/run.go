@@ -0,0 +1,25 @@
+package main
+
+import "context"
+
+func run(ctx context.Context, args []string) int {
+	var opts options
+	var webhook webhookFlags
+	fs := newFlagSet("run", "", "Registers a webhook and polls its state until it finishes.")
+	opts.flags(fs)
+	webhook.flags(fs)
+	interval := fs.Duration("interval", defaultInterval(), "interval between state queries")
+	parse(fs, args)
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	r, release, err := webhook.request(fs)
+	if err != nil {
+		return fail(err)
+	}
+	defer release()
+
+	return follow(client.RegisterAndPollContext(ctx, r, *interval), opts)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/timehook/cli-client/cron"
+)
+
+// occurrence is the JSON document of a time of a recurring webhook, with
+// the ID of the webhook registered for it unless previewed
+type occurrence struct {
+	ID string `json:"id,omitempty"`
+	At string `json:"at"`
+}
+
+func schedule(ctx context.Context, args []string) int {
+	var opts options
+	var webhook webhookFlags
+	fs := newFlagSet("schedule", "", "Registers a webhook for each of the next times of a cron expression.")
+	opts.flags(fs)
+	webhook.flags(fs)
+	expr := fs.String("cron", "", "cron expression of the times to send the webhook, e.g. '0 3 * * *' or @daily, in --tz")
+	count := fs.Int("count", 5, "number of upcoming times to register")
+	preview := fs.Bool("preview", false, "print the upcoming times without registering them")
+	parse(fs, args)
+
+	if *expr == "" {
+		return fail(usagef("--cron is required"))
+	}
+	for _, name := range []string{"sec", "at", "in"} {
+		if isSet(fs, name) {
+			return fail(usagef("--%s can not be used with --cron", name))
+		}
+	}
+	if *count <= 0 {
+		return fail(usagef("--count %d must be positive", *count))
+	}
+	s, err := cron.Parse(*expr)
+	if err != nil {
+		return fail(usageError{err})
+	}
+	loc, err := time.LoadLocation(webhook.tz)
+	if err != nil {
+		return fail(usagef("unknown time zone %q", webhook.tz))
+	}
+	from := time.Now().In(loc)
+
+	if *preview {
+		var occs []occurrence
+		for _, t := range s.NextN(from, *count) {
+			occs = append(occs, occurrence{At: t.Format(time.RFC3339)})
+		}
+		printOccurrences(opts.output, occs)
+		return exitSucceeded
+	}
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	r, release, err := webhook.request(fs)
+	if err != nil {
+		return fail(err)
+	}
+	defer release()
+	r.Delay = 0 // set by the schedule
+
+	registered, err := client.RegisterSchedule(ctx, r, s, from, *count)
+	var occs []occurrence
+	for _, o := range registered {
+		occs = append(occs, occurrence{ID: o.ID, At: o.At.Format(time.RFC3339)})
+	}
+	printOccurrences(opts.output, occs)
+	if err != nil {
+		return fail(err)
+	}
+	return exitSucceeded
+}
+
+// printOccurrences prints the occurrences in the output format given
+func printOccurrences(f format, occs []occurrence) {
+	switch f {
+	case formatJSON:
+		if occs == nil {
+			occs = []occurrence{}
+		}
+		printJSON(f, occs)
+		return
+	case formatJSONL:
+		for _, o := range occs {
+			printJSON(f, o)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for i, o := range occs {
+		switch {
+		case o.ID == "":
+			fmt.Fprintln(w, o.At)
+		case i == 0:
+			fmt.Fprintln(w, "ID\tAT")
+			fallthrough
+		default:
+			fmt.Fprintf(w, "%s\t%s\n", o.ID, o.At)
+		}
+	}
+	w.Flush()
+}
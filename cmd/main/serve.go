@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/timehook/cli-client/emulator"
+)
+
+func serve(ctx context.Context, args []string) int {
+	fs := newFlagSet("serve", "", "Runs an emulator of the Timehook API which sends the webhooks registered, for tests and offline use.")
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	keys := fs.String("keys", "", "comma separated API keys accepted, any key when empty")
+	rate := fs.Int("rate-limit", 0, "requests allowed per API key every --rate-window, no limit when 0")
+	window := fs.Duration("rate-window", time.Second, "window of the rate limit")
+	timeout := fs.Duration("timeout", 10*time.Second, "time the webhook target has to respond before the webhook times out")
+	parse(fs, args)
+	if *rate < 0 || *window <= 0 || *timeout <= 0 {
+		return fail(usagef("--rate-limit, --rate-window and --timeout must be positive"))
+	}
+
+	opts := []emulator.Option{emulator.WithRateLimit(*rate, *window), emulator.WithTimeout(*timeout)}
+	if *keys != "" {
+		opts = append(opts, emulator.WithKeys(strings.Split(*keys, ",")...))
+	}
+	e := emulator.New(opts...)
+	defer e.Close()
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fail(err)
+	}
+	srv := &http.Server{Handler: e}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Timehook API emulator listening on http://%s\n", ln.Addr())
+	fmt.Printf("Use it with TIMEHOOK_API_URL=http://%s\n", ln.Addr())
+	if err := srv.Serve(ln); err != http.ErrServerClosed {
+		return fail(err)
+	}
+	return exitSucceeded
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func status(ctx context.Context, args []string) int {
+	var opts options
+	fs := newFlagSet("status", "<id>", "Prints the state of the webhook identified by id.")
+	opts.flags(fs)
+	parse(fs, args)
+	ID, ok := webhookID(fs)
+	if !ok {
+		return exitUsage
+	}
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	sr, err := client.State(ctx, ID)
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.output != formatText {
+		printJSON(opts.output, sr)
+		return exitSucceeded
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, f := range [][2]string{
+		{"id", sr.ID},
+		{"status", string(sr.Status)},
+		{"registered at", sr.RegisteredAt},
+		{"scheduled at", sr.ScheduledAt},
+		{"awaiting clock at", sr.AwaitingClockAt},
+		{"sending http at", sr.SendingHttpAt},
+		{"succeeded at", sr.SucceededAt},
+		{"failed at", sr.FailedAt},
+	} {
+		if f[1] != "" {
+			fmt.Fprintf(w, "%s\t%s\n", f[0], f[1])
+		}
+	}
+	w.Flush()
+
+	return exitSucceeded
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package main
+
+import "os"
+
+// isTerminal returns if f is a character device. It is an approximation on
+// these systems: other character devices, such as the null device, are taken
+// as terminals as well, so use --progress plain when redirecting to them.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer devNull.Close()
+	file, err := ioutil.TempFile("", "terminal")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	for _, f := range []*os.File{devNull, file} {
+		if isTerminal(f) {
+			t.Errorf("%s taken as a terminal", f.Name())
+		}
+	}
+}
@@ -1,34 +1,285 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/timehook/cli-client/config"
 	"github.com/timehook/cli-client/timehook"
 )
 
+// command is a subcommand of the CLI. run parses args and returns the exit
+// code.
+type command struct {
+	name  string
+	short string
+	run   func(ctx context.Context, args []string) int
+}
+
+var commands = []command{
+	{"run", "register a webhook and poll its state until it finishes (default)", run},
+	{"register", "register a webhook and print its ID", register},
+	{"status", "print the state of a webhook", status},
+	{"watch", "poll the state of registered webhooks until they finish", watch},
+	{"cancel", "cancel a registered webhook", cancel},
+	{"list", "list the webhooks registered", list},
+	{"schedule", "register the upcoming times of a recurring webhook given by a cron expression", schedule},
+	{"batch", "register the webhooks of a JSON lines file and poll them until they finish", registerBatch},
+	{"serve", "run an emulator of the Timehook API for tests and offline use", serve},
+	{"config", "view or edit the configuration profiles", configure},
+	{"receive", "listen for webhooks, print them and check the one expected arrives in time", receive},
+}
+
+// withoutProfile are the commands which do not use the profile, which
+// config loads itself
+var withoutProfile = map[string]bool{"config": true, "serve": true}
+
 func main() {
-	URL := flag.String("url", "https://httpstat.us/200", "webhook URL")
-	body := flag.String("body", `{"msg" : "from timehook client"}`, "webhook body in JSON")
-	sec := flag.Int("sec", 5, "delay in seconds")
-	flag.Parse()
+	ctx, stop := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		stop()
+	}()
+
+	args := os.Args[1:]
+	if len(args) == 0 || !withoutProfile[args[0]] {
+		p, err := loadProfile(args)
+		if err != nil {
+			os.Exit(fail(err))
+		}
+		profile = p
+	}
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		os.Exit(run(ctx, args))
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			os.Exit(c.run(ctx, args[1:]))
+		}
+	}
+
+	if args[0] != "help" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	}
+	usage()
+	os.Exit(exitUsage)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: timehook <command> [flags] [args]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.short)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'timehook <command> --help' for the flags of a command.\n")
+}
+
+// newFlagSet returns the flag set of a command described by its arguments
+// and one line description
+func newFlagSet(name, args, short string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: timehook %s [flags] %s\n\n%s\n\nFlags:\n", name, args, short)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of args, also those given after the arguments of
+// the command, as in watch <id> --progress plain. Arguments after -- are
+// not parsed.
+func parse(fs *flag.FlagSet, args []string) {
+	var params []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			params = append(params, rest...)
+			break
+		}
+		params = append(params, rest[0])
+		args = rest[1:]
+	}
+	fs.Parse(append([]string{"--"}, params...))
+}
+
+// options are the flags shared by the commands talking with the API
+type options struct {
+	apiURL     string
+	keyFile    string
+	keyCommand string
+	verbose    bool
+	output     format
+	progress   progress
+}
 
-	if os.Getenv("TIMEHOOK_KEY") == "" {
-		fmt.Println("TIMEHOOK_KEY enviroment variable not defined")
-		os.Exit(1)
+func (o *options) flags(fs *flag.FlagSet) {
+	profileFlag(fs)
+	fs.StringVar(&o.apiURL, "api-url", apiURL(), "Timehook API base URL, defaults to TIMEHOOK_API_URL environment variable or the profile if defined")
+	fs.StringVar(&o.keyFile, "key-file", "", "file holding the API key, not accessible by other users, instead of TIMEHOOK_KEY")
+	fs.StringVar(&o.keyCommand, "key-command", "", "credential helper command printing the API key, run with the shell, instead of TIMEHOOK_KEY")
+	fs.BoolVar(&o.verbose, "verbose", false, "print where the API key and URL are taken from on the standard error, never the key")
+	o.output = formatText
+	fs.Var(&o.output, "output", "output format: text, json or jsonl")
+	o.progress = progressAuto
+	fs.Var(&o.progress, "progress", "progress of the text format: tty, plain with timestamps, quiet with only the result, or auto for tty on a terminal and plain otherwise")
+}
+
+// keyCommandTimeout bounds how long the credential helper command can take
+// to print the API key
+const keyCommandTimeout = 30 * time.Second
+
+// client returns a Timehook client configured with the options
+func (o *options) client(ctx context.Context) (*timehook.Client, error) {
+	key, from, err := o.apiKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if o.verbose {
+		fmt.Fprintf(os.Stderr, "[Verbose] API %s, API key from %s\n", o.apiURL, from)
 	}
+	return timehook.New(key, http.DefaultClient, timehook.WithBaseURL(o.apiURL)), nil
+}
+
+// keySource are the ways to give the API key at a level of the settings
+type keySource struct {
+	level, key, file, command string
+}
 
-	client := timehook.New(os.Getenv("TIMEHOOK_KEY"), http.DefaultClient)
-	proc := client.RegisterAndPoll(*URL, *body, *sec, 1*time.Second)
-	for msg := range proc.C {
-		fmt.Fprint(os.Stdout, msg)
+// apiKey returns the API key and where it is taken from: the flags, the
+// environment or the profile, in this order, each one with the key itself,
+// a key file or a credential helper command, which is killed when ctx is
+// done or after keyCommandTimeout
+func (o *options) apiKey(ctx context.Context) (string, string, error) {
+	if o.keyFile != "" && o.keyCommand != "" {
+		return "", "", usagef("only one of --key-file and --key-command can be used")
 	}
+	for _, s := range []keySource{
+		{"flag", "", o.keyFile, o.keyCommand},
+		{"environment", os.Getenv("TIMEHOOK_KEY"), os.Getenv("TIMEHOOK_KEY_FILE"), os.Getenv("TIMEHOOK_KEY_COMMAND")},
+		{"profile " + profile.Name, profile.Key, profile.KeyFile, profile.KeyCommand},
+	} {
+		switch {
+		case s.key != "":
+			return s.key, s.level, nil
+		case s.file != "":
+			key, err := config.ReadKeyFile(s.file)
+			return key, s.level + " key file " + s.file, err
+		case s.command != "":
+			ctx, cancel := context.WithTimeout(ctx, keyCommandTimeout)
+			defer cancel()
+			key, err := config.RunKeyCommand(ctx, s.command,
+				"TIMEHOOK_PROFILE="+profile.Name, "TIMEHOOK_API_URL="+o.apiURL)
+			return key, s.level + " key command", err
+		}
+	}
+	return "", "", errors.New("no API key, define TIMEHOOK_KEY, TIMEHOOK_KEY_FILE or TIMEHOOK_KEY_COMMAND environment variable or the key in the profile")
+}
+
+// apiURL returns the API base URL from the environment, the profile or the
+// default one
+func apiURL() string {
+	if u := os.Getenv("TIMEHOOK_API_URL"); u != "" {
+		return u
+	}
+	if profile.APIURL != "" {
+		return profile.APIURL
+	}
+	return timehook.DefaultBaseURL
+}
 
-	if proc.IsSucceeded() {
-		os.Exit(0)
+// webhookID returns the webhook ID given as only argument of the command
+func webhookID(fs *flag.FlagSet) (string, bool) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", false
 	}
-	os.Exit(1)
+	return fs.Arg(0), true
+}
+
+// follow prints the process until it finishes in the output format of the
+// options and returns the exit code of its result. The text format renders
+// the progress, jsonl prints a line per state transition and json the final
+// result.
+func follow(proc *timehook.RegisterAnPollProcess, opts options) int {
+	return followEvents(proc.C, opts, false, func() int {
+		res, err := proc.Result()
+		if opts.output == formatJSON {
+			printJSON(opts.output, newResult(res, err))
+		}
+		return outcomeCode(res.Outcome, err)
+	})
+}
+
+// followGroup prints the group until every webhook finishes in the output
+// format of the options and returns the exit code of the first webhook not
+// succeeded. The text format renders the progress and a summary, jsonl
+// prints a line per state transition and json the final results.
+func followGroup(g *timehook.Group, opts options) int {
+	return followEvents(g.C, opts, true, func() int {
+		res := g.Result()
+		switch opts.output {
+		case formatJSON:
+			var docs []result
+			for _, r := range res.Results {
+				docs = append(docs, newResult(r, res.Errors[r.WebhookID]))
+			}
+			printJSON(opts.output, struct {
+				Results []result                 `json:"results"`
+				Summary map[timehook.Outcome]int `json:"summary"`
+			}{docs, res.Counts})
+		case formatText:
+			fmt.Printf("\n%s\n", summary(res.Counts))
+		}
+
+		for _, r := range res.Results {
+			if r.Outcome != timehook.OutcomeSucceeded {
+				return outcomeCode(r.Outcome, res.Errors[r.WebhookID])
+			}
+		}
+		return exitSucceeded
+	})
+}
+
+// followEvents prints the events until C is closed and returns the exit
+// code returned by result
+func followEvents(C <-chan timehook.Event, opts options, many bool, result func() int) int {
+	var r timehook.Renderer
+	if opts.output == formatText {
+		r = opts.progress.renderer(many)
+	}
+	last := map[string]timehook.EventKind{}
+	for e := range C {
+		switch {
+		case r != nil:
+			r.Render(e)
+		case opts.output == formatJSONL && !(e.Kind == timehook.EventAwaiting && last[e.WebhookID] == timehook.EventAwaiting):
+			printJSON(opts.output, newEvent(e))
+		}
+		last[e.WebhookID] = e.Kind
+	}
+	if r != nil {
+		r.Close()
+	}
+	return result()
+}
+
+// fail prints the error and returns the exit code of a failed command
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "[Error] %s\n", err)
+	return errorCode(err)
 }
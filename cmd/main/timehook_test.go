@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		name       string
+		given      []string
+		wantParams []string
+		wantOutput string
+	}{
+		{name: "flags first", given: []string{"--output", "json", "abc"}, wantParams: []string{"abc"}, wantOutput: "json"},
+		{name: "flags after", given: []string{"abc", "--output", "json", "def"}, wantParams: []string{"abc", "def"}, wantOutput: "json"},
+		{name: "stdin", given: []string{"-", "-output=json"}, wantParams: []string{"-"}, wantOutput: "json"},
+		{name: "terminator", given: []string{"abc", "--", "--output", "json"}, wantParams: []string{"abc", "--output", "json"}, wantOutput: "text"},
+		{name: "no arguments", given: nil, wantParams: []string{}, wantOutput: "text"},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			// given
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			output := fs.String("output", "text", "")
+
+			// when
+			parse(fs, v.given)
+
+			// then
+			if !reflect.DeepEqual(fs.Args(), v.wantParams) {
+				t.Errorf("wrong arguments want %q got %q", v.wantParams, fs.Args())
+			}
+			if *output != v.wantOutput {
+				t.Errorf("wrong flag want %s got %s", v.wantOutput, *output)
+			}
+		})
+	}
+}
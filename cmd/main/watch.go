@@ -0,0 +1,25 @@
+package main
+
+import "context"
+
+func watch(ctx context.Context, args []string) int {
+	var opts options
+	fs := newFlagSet("watch", "<id> [id...]", "Polls the state of the webhooks identified by the ids, together, until every one finishes.")
+	opts.flags(fs)
+	interval := fs.Duration("interval", defaultInterval(), "interval between state queries")
+	parse(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := opts.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	if fs.NArg() == 1 {
+		return follow(client.Watch(ctx, fs.Arg(0), *interval), opts)
+	}
+	return followGroup(client.WatchGroup(ctx, fs.Args(), *interval), opts)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+// webhookFlags are the flags describing the webhook to register
+type webhookFlags struct {
+	URL         string
+	body        string
+	bodyFile    string
+	compact     bool
+	pretty      bool
+	noValidate  bool
+	method      string
+	contentType string
+	headers     headerFlag
+	sec         int
+	at          string
+	in          time.Duration
+	tz          string
+}
+
+func (w *webhookFlags) flags(fs *flag.FlagSet) {
+	fs.StringVar(&w.URL, "url", defaultURL(), "webhook URL, defaults to the one of the profile if defined")
+	fs.StringVar(&w.body, "body", `{"msg" : "from timehook client"}`, "webhook body in JSON, - to read it from the standard input")
+	fs.StringVar(&w.bodyFile, "body-file", "", "file with the webhook body, - for the standard input")
+	fs.BoolVar(&w.compact, "compact", false, "remove insignificant spaces from the JSON body")
+	fs.BoolVar(&w.pretty, "pretty", false, "indent the JSON body")
+	fs.BoolVar(&w.noValidate, "no-validate", false, "send the body as it is, without validating it is JSON")
+	fs.StringVar(&w.method, "method", "POST", "HTTP method of the webhook: GET, POST, PUT, PATCH or DELETE")
+	fs.StringVar(&w.contentType, "content-type", "application/json", "media type of the webhook body, only JSON bodies are validated")
+	fs.Var(&w.headers, "header", "header 'Name: value' forwarded to the webhook target, can be repeated, added to the ones of the profile")
+	fs.IntVar(&w.sec, "sec", 5, "delay in seconds")
+	fs.StringVar(&w.at, "at", "", "send the webhook at a date, e.g. 2026-11-01T09:00:00+02:00, in --tz when it has no offset")
+	fs.DurationVar(&w.in, "in", 0, "send the webhook after a duration, e.g. 2h30m")
+	fs.StringVar(&w.tz, "tz", "Local", "time zone of the --at dates without offset and of --cron, e.g. Europe/Madrid")
+}
+
+// schedule sets when the webhook described by the flags parsed in fs is sent
+func (w *webhookFlags) schedule(fs *flag.FlagSet, r *timehook.RegisterRequest) error {
+	n := 0
+	for _, name := range []string{"sec", "at", "in"} {
+		if isSet(fs, name) {
+			n++
+		}
+	}
+	if n > 1 {
+		return usagef("only one of --sec, --at and --in can be used")
+	}
+
+	switch {
+	case isSet(fs, "at"):
+		loc, err := time.LoadLocation(w.tz)
+		if err != nil {
+			return usagef("unknown time zone %q", w.tz)
+		}
+		at, err := timehook.ParseTimeIn(w.at, loc)
+		if err != nil {
+			return usageError{err}
+		}
+		if !at.After(time.Now()) {
+			return usagef("--at %s is in the past", at.Format(time.RFC3339))
+		}
+		r.At = at
+	case isSet(fs, "in"):
+		if w.in < 0 {
+			return usagef("--in %s is negative", w.in)
+		}
+		r.Delay = w.in
+	default:
+		if w.sec < 0 {
+			return usagef("--sec %d is negative", w.sec)
+		}
+		r.Delay = time.Duration(w.sec) * time.Second
+	}
+
+	return nil
+}
+
+// defaultURL returns the webhook URL of the profile or the default one
+func defaultURL() string {
+	if profile.URL != "" {
+		return profile.URL
+	}
+	return "https://httpstat.us/200"
+}
+
+// mergeHeaders returns the headers of the profile replaced by the ones given
+// with the same name in the flags
+func (w *webhookFlags) mergeHeaders() http.Header {
+	h := http.Header{}
+	for name, v := range profile.Headers {
+		h.Set(name, v)
+	}
+	for name, values := range w.headers {
+		h[name] = values
+	}
+	if len(h) == 0 {
+		return nil
+	}
+	return h
+}
+
+// headerFlag collects the headers given with a repeatable flag
+type headerFlag http.Header
+
+func (h *headerFlag) String() string { return "" }
+
+func (h *headerFlag) Set(v string) error {
+	i := strings.Index(v, ":")
+	if i <= 0 {
+		return fmt.Errorf("header %q is not in the form 'Name: value'", v)
+	}
+	name := strings.TrimSpace(v[:i])
+	if strings.EqualFold(name, "Content-Type") {
+		return errors.New("use --content-type to set the Content-Type header")
+	}
+	if *h == nil {
+		*h = headerFlag{}
+	}
+	http.Header(*h).Add(name, strings.TrimSpace(v[i+1:]))
+	return nil
+}
+
+// request returns the request to register the webhook described by the
+// flags parsed in fs, and a function to release its body once registered.
+// JSON bodies are validated, and normalized if asked, unless validation is
+// disabled. Other bodies are streamed as they are.
+func (w *webhookFlags) request(fs *flag.FlagSet) (*timehook.RegisterRequest, func(), error) {
+	switch {
+	case w.bodyFile != "" && isSet(fs, "body"):
+		return nil, nil, usagef("--body and --body-file can not be used together")
+	case w.compact && w.pretty:
+		return nil, nil, usagef("--compact and --pretty can not be used together")
+	case w.noValidate && (w.compact || w.pretty):
+		return nil, nil, usagef("--no-validate can not be used with --compact or --pretty")
+	case !timehook.IsJSON(w.contentType) && (w.compact || w.pretty):
+		return nil, nil, usagef("--compact and --pretty need a JSON content type, got %q", w.contentType)
+	}
+	method := strings.ToUpper(w.method)
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return nil, nil, usagef("unsupported method %q", w.method)
+	}
+
+	body, err := w.openBody()
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &timehook.RegisterRequest{
+		URL:         w.URL,
+		Body:        body,
+		Method:      method,
+		ContentType: w.contentType,
+		Headers:     w.mergeHeaders(),
+	}
+	if err := w.schedule(fs, r); err != nil {
+		body.Close()
+		return nil, nil, err
+	}
+	if w.noValidate || !timehook.IsJSON(w.contentType) {
+		return r, func() { body.Close() }, nil
+	}
+
+	b, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not read body: %s", err)
+	}
+	switch {
+	case w.compact:
+		b, err = timehook.CompactJSON(b)
+	case w.pretty:
+		b, err = timehook.IndentJSON(b)
+	default:
+		err = timehook.ValidateJSON(b)
+	}
+	if err != nil {
+		return nil, nil, usageError{err}
+	}
+
+	r.Body = bytes.NewReader(b)
+	return r, func() {}, nil
+}
+
+// openBody opens the body given inline, in a file or in the standard input
+func (w *webhookFlags) openBody() (io.ReadCloser, error) {
+	path := w.bodyFile
+	if path == "" && w.body == "-" {
+		path = "-"
+	}
+
+	switch path {
+	case "":
+		return ioutil.NopCloser(strings.NewReader(w.body)), nil
+	case "-":
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+// isSet returns if the flag name has been set in the command line
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
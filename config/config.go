@@ -0,0 +1,285 @@
+// Package config reads and writes the configuration file of the Timehook
+// CLI, with named profiles of settings. The file is written in a subset of
+// TOML:
+//
+//	# profile used when none is given
+//	profile = "staging"
+//
+//	[production]
+//	key = "__PRODUCTION_KEY__"
+//	url = "https://your-url.com/hook"
+//	interval = "2s"
+//
+//	[production.headers]
+//	Authorization = "Bearer __TARGET_TOKEN__"
+//
+//	[staging]
+//	key_command = "pass show timehook/staging"
+//	api_url = "https://staging.timehook.io"
+//
+// Rather than the key itself, a profile may give a key_file holding it or a
+// key_command printing it, a credential helper.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of the CLI
+type Config struct {
+	// Default is the name of the profile used when none is given
+	Default  string
+	Profiles map[string]*Profile
+}
+
+// Profile are the settings used together, e.g. for an environment
+type Profile struct {
+	Name string
+	// Key is the Timehook API key
+	Key string
+	// KeyFile is the path of a file holding the API key
+	KeyFile string
+	// KeyCommand is a credential helper printing the API key
+	KeyCommand string
+	// APIURL is the base URL of the Timehook API
+	APIURL string
+	// URL is the default webhook target
+	URL string
+	// Headers are forwarded to the webhook target by default
+	Headers map[string]string
+	// Interval is the default interval between state queries
+	Interval time.Duration
+}
+
+// DefaultProfile is the name of the profile used when none is given nor set
+// as default in the file
+const DefaultProfile = "default"
+
+// Settings are the names of the settings of a profile, headers are set as
+// header.<Name>
+var Settings = []string{"key", "key_file", "key_command", "api_url", "url", "interval", "header.<Name>"}
+
+// ErrKeyFileMode is returned reading a key file accessible by other users
+var ErrKeyFileMode = errors.New("it is accessible by other users, restrict it with chmod 600")
+
+// DefaultPath returns the path of the configuration file, given by the
+// TIMEHOOK_CONFIG environment variable or timehook/config.toml in the user
+// configuration directory, e.g. ~/.config/timehook/config.toml
+func DefaultPath() (string, error) {
+	if p := os.Getenv("TIMEHOOK_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("can not find the configuration directory: %s", err)
+	}
+	return filepath.Join(dir, "timehook", "config.toml"), nil
+}
+
+// Load reads the configuration file at path. A file which does not exist is
+// an empty configuration.
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Config{Profiles: make(map[string]*Profile)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+// Parse parses a configuration
+func Parse(r io.Reader) (*Config, error) {
+	c := &Config{Profiles: make(map[string]*Profile)}
+	err := parseTOML(r, func(table []string, key string, v interface{}) error {
+		switch {
+		case len(table) == 0 && key == "profile":
+			s, ok := v.(string)
+			if !ok {
+				return errors.New("profile must be a string")
+			}
+			c.Default = s
+			return nil
+		case len(table) == 1:
+			return c.profile(table[0]).set(key, v)
+		case len(table) == 2 && table[1] == "headers":
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("header %s must be a string", key)
+			}
+			c.profile(table[0]).Headers[key] = s
+			return nil
+		}
+		return fmt.Errorf("unknown setting %s", strings.Join(append(table, key), "."))
+	})
+	return c, err
+}
+
+// profile returns the profile named, creating it if it does not exist
+func (c *Config) profile(name string) *Profile {
+	p, ok := c.Profiles[name]
+	if !ok {
+		p = &Profile{Name: name, Headers: make(map[string]string)}
+		c.Profiles[name] = p
+	}
+	return p
+}
+
+// set sets the setting of the profile given as a value of the file
+func (p *Profile) set(key string, v interface{}) error {
+	if key == "interval" {
+		switch v := v.(type) {
+		case int64:
+			p.Interval = time.Duration(v) * time.Second
+			return nil
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("wrong interval %q", v)
+			}
+			p.Interval = d
+			return nil
+		}
+		return errors.New("interval must be a duration, e.g. \"2s\"")
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a string", key)
+	}
+	switch key {
+	case "key":
+		p.Key = s
+	case "key_file":
+		p.KeyFile = s
+	case "key_command":
+		p.KeyCommand = s
+	case "api_url":
+		p.APIURL = s
+	case "url":
+		p.URL = s
+	default:
+		return fmt.Errorf("unknown setting %s", key)
+	}
+	return nil
+}
+
+// Profile returns the profile named, or the default one when name is empty.
+// Without default profile it returns the one named DefaultProfile, or an
+// empty one when there is none.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		if p, ok := c.Profiles[DefaultProfile]; ok {
+			return p, nil
+		}
+		return &Profile{Headers: make(map[string]string)}, nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	return p, nil
+}
+
+// Set sets the setting of the profile named, creating it if it does not
+// exist. name is the default profile when empty. Headers are set as
+// header.<Name>.
+func (c *Config) Set(name, setting, value string) error {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		name = DefaultProfile
+	}
+	if !isBareKey(name) {
+		return fmt.Errorf("wrong profile name %q, use letters, digits, - and _", name)
+	}
+
+	p := c.profile(name)
+	if strings.HasPrefix(setting, "header.") {
+		p.Headers[strings.TrimPrefix(setting, "header.")] = value
+		return nil
+	}
+	return p.set(setting, value)
+}
+
+// Write writes the configuration as a file. Profiles are sorted by name and
+// comments are not kept.
+func (c *Config) Write(w io.Writer) error {
+	var b bytes.Buffer
+	if c.Default != "" {
+		fmt.Fprintf(&b, "profile = %s\n", quote(c.Default))
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := c.Profiles[name]
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n", key(name))
+		for _, s := range []struct{ key, value string }{
+			{"key", p.Key}, {"key_file", p.KeyFile}, {"key_command", p.KeyCommand}, {"api_url", p.APIURL}, {"url", p.URL},
+		} {
+			if s.value != "" {
+				fmt.Fprintf(&b, "%s = %s\n", s.key, quote(s.value))
+			}
+		}
+		if p.Interval > 0 {
+			fmt.Fprintf(&b, "interval = %s\n", quote(p.Interval.String()))
+		}
+
+		if len(p.Headers) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n[%s.headers]\n", key(name))
+		headers := make([]string, 0, len(p.Headers))
+		for h := range p.Headers {
+			headers = append(headers, h)
+		}
+		sort.Strings(headers)
+		for _, h := range headers {
+			fmt.Fprintf(&b, "%s = %s\n", key(h), quote(p.Headers[h]))
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Save writes the configuration in the file at path, readable only by the
+// user as it holds API keys
+func (c *Config) Save(path string) error {
+	var b bytes.Buffer
+	if err := c.Write(&b); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b.Bytes(), 0600)
+}
@@ -0,0 +1,226 @@
+package config_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/config"
+)
+
+const file = `# profile used when none is given
+profile = "staging"
+
+[production]
+key = "prod-key" # the key
+url = 'https://your-url.com/hook'
+interval = "2s"
+
+[production.headers]
+Authorization = "Bearer \"token\""
+"X-Request-ID" = "42"
+
+[staging]
+key = "staging-key"
+api_url = "https://staging.timehook.io"
+interval = 5
+`
+
+func TestParse(t *testing.T) {
+	// when
+	c, err := config.Parse(strings.NewReader(file))
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := &config.Config{
+		Default: "staging",
+		Profiles: map[string]*config.Profile{
+			"production": {
+				Name:     "production",
+				Key:      "prod-key",
+				URL:      "https://your-url.com/hook",
+				Interval: 2 * time.Second,
+				Headers:  map[string]string{"Authorization": `Bearer "token"`, "X-Request-ID": "42"},
+			},
+			"staging": {
+				Name:     "staging",
+				Key:      "staging-key",
+				APIURL:   "https://staging.timehook.io",
+				Interval: 5 * time.Second,
+				Headers:  map[string]string{},
+			},
+		},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("wrong config want %+v got %+v", want, c)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	tt := []struct {
+		given string
+		want  string
+	}{
+		{given: "[p]\nkey = 42", want: "line 2: key must be a string"},
+		{given: "[p]\nfoo = \"bar\"", want: "line 2: unknown setting foo"},
+		{given: "[p.other]\nfoo = \"bar\"", want: "line 2: unknown setting p.other.foo"},
+		{given: "[p]\nkey = \"a\"\nkey = \"b\"", want: "line 3: key p.key defined twice"},
+		{given: "[p]\n[p]", want: "line 2: table p defined twice"},
+		{given: "[p]\nkey = \"a", want: "line 2: unterminated string"},
+		{given: "[p]\nkey = \"a\" b", want: `line 2: unexpected "b"`},
+		{given: "[p]\ninterval = 2s", want: "line 2: unsupported value 2s, use a string, integer or boolean"},
+		{given: "[p]\ninterval = \"soon\"", want: `line 2: wrong interval "soon"`},
+		{given: "[p\nkey = \"a\"", want: "line 1: wrong table [p"},
+		{given: "key", want: "line 1: = expected after key key"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.want, func(t *testing.T) {
+			// when
+			_, err := config.Parse(strings.NewReader(tc.given))
+
+			// then
+			if err == nil || err.Error() != tc.want {
+				t.Errorf("wrong error want %s got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Profile(t *testing.T) {
+	c, _ := config.Parse(strings.NewReader(file))
+	tt := []struct {
+		name    string
+		given   string
+		want    string
+		wantErr bool
+	}{
+		{name: "named", given: "production", want: "prod-key"},
+		{name: "default", given: "", want: "staging-key"},
+		{name: "unknown", given: "sandbox", wantErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			p, err := c.Profile(tc.given)
+
+			// then
+			if tc.wantErr {
+				if err == nil {
+					t.Error("error expected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if p.Key != tc.want {
+				t.Errorf("wrong key want %s got %s", tc.want, p.Key)
+			}
+		})
+	}
+}
+
+func TestConfig_ProfileWithoutDefault(t *testing.T) {
+	// given
+	c, _ := config.Parse(strings.NewReader("[default]\nkey = \"the-key\""))
+	empty, _ := config.Parse(strings.NewReader(""))
+
+	// when
+	p, err := c.Profile("")
+	e, emptyErr := empty.Profile("")
+
+	// then
+	if err != nil || p.Key != "the-key" {
+		t.Errorf("wrong default profile %+v, %v", p, err)
+	}
+	if emptyErr != nil || e.Key != "" {
+		t.Errorf("wrong empty profile %+v, %v", e, emptyErr)
+	}
+}
+
+func TestConfig_SetAndWrite(t *testing.T) {
+	// given
+	c, _ := config.Parse(strings.NewReader(file))
+
+	// when
+	for _, s := range [][3]string{
+		{"sandbox", "key", "sandbox-key"},
+		{"sandbox", "header.X-Team", "payments"},
+		{"sandbox", "key_file", "/home/me/.timehook/key"},
+		{"sandbox", "key_command", "pass show 'timehook/sandbox'"},
+		{"", "interval", "1m"},
+	} {
+		if err := c.Set(s[0], s[1], s[2]); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	var b bytes.Buffer
+	c.Write(&b)
+	got, err := config.Parse(&b)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error parsing %s: %s", b.String(), err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("configuration written not the same want %+v got %+v", c, got)
+	}
+	if got.Profiles["sandbox"].Headers["X-Team"] != "payments" {
+		t.Errorf("wrong header want %s got %s", "payments", got.Profiles["sandbox"].Headers["X-Team"])
+	}
+	if got.Profiles["staging"].Interval != time.Minute {
+		t.Errorf("wrong interval of default profile want %s got %s", time.Minute, got.Profiles["staging"].Interval)
+	}
+}
+
+func TestConfig_SetError(t *testing.T) {
+	c, _ := config.Parse(strings.NewReader(""))
+	for _, s := range [][3]string{
+		{"", "foo", "bar"},
+		{"", "interval", "soon"},
+		{"with space", "key", "the-key"},
+	} {
+		if err := c.Set(s[0], s[1], s[2]); err == nil {
+			t.Errorf("error expected setting %v", s)
+		}
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "timehook", "config.toml")
+	c, _ := config.Load(path)
+
+	// when
+	c.Set("", "key", "the-key")
+	err = c.Save(path)
+	loaded, loadErr := config.Load(path)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if loadErr != nil {
+		t.Fatalf("unexpected error: %s", loadErr)
+	}
+	if p, _ := loaded.Profile(""); p.Key != "the-key" {
+		t.Errorf("wrong key want %s got %s", "the-key", p.Key)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Mode().Perm() != 0600 {
+		t.Errorf("wrong permissions want %o got %o", 0600, fi.Mode().Perm())
+	}
+}
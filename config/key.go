@@ -0,0 +1,100 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ReadKeyFile reads the API key from the file at path, which must hold only
+// the key. A leading ~/ in path is the home directory. Files readable by
+// other users are refused, apart from Windows where permissions are not
+// checked. The key is never part of the errors.
+func ReadKeyFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if err := checkKeyFile(path, fi); err != nil {
+		return "", err
+	}
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(b))
+	switch {
+	case key == "":
+		return "", fmt.Errorf("key file %s is empty", path)
+	case strings.ContainsAny(key, "\r\n"):
+		return "", fmt.Errorf("key file %s must hold only the key", path)
+	}
+	return key, nil
+}
+
+// RunKeyCommand runs the credential helper command with the shell and
+// returns the API key, the first line it prints. env are added to the
+// environment of the command, e.g. TIMEHOOK_PROFILE=production. Errors
+// carry what the command prints on the standard error, never the key.
+// The command is killed when ctx is done, along the processes it starts
+// apart from Windows.
+func RunKeyCommand(ctx context.Context, command string, env ...string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.Env = append(os.Environ(), env...)
+	newProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("key command %q failed: %s", command, err)
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("key command %q failed: %w", command, ctx.Err())
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s: %s", err, msg)
+		}
+		return "", fmt.Errorf("key command %q failed: %s", command, err)
+	}
+
+	key := strings.TrimSpace(strings.SplitN(stdout.String(), "\n", 2)[0])
+	if key == "" {
+		return "", fmt.Errorf("key command %q printed no key", command)
+	}
+	return key, nil
+}
@@ -0,0 +1,165 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/config"
+)
+
+func TestReadKeyFile(t *testing.T) {
+	tt := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		want    string
+		wantErr string
+	}{
+		{name: "key", content: "the-key\n", mode: 0600, want: "the-key"},
+		{name: "read only", content: "  the-key  ", mode: 0400, want: "the-key"},
+		{name: "empty", content: "\n", mode: 0600, wantErr: "is empty"},
+		{name: "many lines", content: "the-key\nother-key\n", mode: 0600, wantErr: "only the key"},
+		{name: "world readable", content: "the-key", mode: 0644, wantErr: "mode 0644"},
+		{name: "group readable", content: "the-key", mode: 0640, wantErr: "mode 0640"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && tc.mode&0077 != 0 {
+				t.Skip("permissions are not checked on windows")
+			}
+
+			// given
+			dir, err := ioutil.TempDir("", "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "key")
+			if err := ioutil.WriteFile(path, []byte(tc.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			os.Chmod(path, tc.mode)
+
+			// when
+			key, err := config.ReadKeyFile(path)
+
+			// then
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error containing %q expected, got %v", tc.wantErr, err)
+				}
+				if strings.Contains(err.Error(), "the-key") {
+					t.Errorf("error shows the key: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if key != tc.want {
+				t.Errorf("wrong key want %q got %q", tc.want, key)
+			}
+		})
+	}
+}
+
+func TestReadKeyFile_Mode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not checked on windows")
+	}
+
+	// given
+	dir, err := ioutil.TempDir("", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "key")
+	ioutil.WriteFile(path, []byte("the-key"), 0600)
+	os.Chmod(path, 0604)
+
+	// when
+	_, err = config.ReadKeyFile(path)
+
+	// then
+	if !errors.Is(err, config.ErrKeyFileMode) {
+		t.Errorf("error expected %v got %v", config.ErrKeyFileMode, err)
+	}
+}
+
+func TestReadKeyFile_NotFound(t *testing.T) {
+	_, err := config.ReadKeyFile(filepath.Join(os.TempDir(), "timehook-no-such-key"))
+	if !os.IsNotExist(err) {
+		t.Errorf("not exist error expected got %v", err)
+	}
+}
+
+func TestRunKeyCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are run with sh")
+	}
+	tt := []struct {
+		name    string
+		command string
+		env     []string
+		want    string
+		wantErr string
+	}{
+		{name: "key", command: "echo the-key", want: "the-key"},
+		{name: "first line", command: "printf 'the-key\\nmetadata\\n'", want: "the-key"},
+		{name: "environment", command: "echo key-of-$TIMEHOOK_PROFILE", env: []string{"TIMEHOOK_PROFILE=staging"}, want: "key-of-staging"},
+		{name: "no key", command: "true", wantErr: "printed no key"},
+		{name: "failed", command: "echo the-key; echo 'vault is sealed' >&2; exit 3", wantErr: "vault is sealed"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			key, err := config.RunKeyCommand(context.Background(), tc.command, tc.env...)
+
+			// then
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error containing %q expected, got %v", tc.wantErr, err)
+				}
+				if strings.Contains(strings.Replace(err.Error(), tc.command, "", 1), "the-key") {
+					t.Errorf("error shows the key: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if key != tc.want {
+				t.Errorf("wrong key want %q got %q", tc.want, key)
+			}
+		})
+	}
+}
+
+func TestRunKeyCommand_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands are run with sh")
+	}
+	// given
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+
+	// when
+	_, err := config.RunKeyCommand(ctx, "sleep 10; echo the-key")
+
+	// then
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong error want %s got %v", context.DeadlineExceeded, err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("command not killed on time, returned after %s", d)
+	}
+}
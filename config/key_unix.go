@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// checkKeyFile refuses key files accessible by the group or other users
+func checkKeyFile(path string, fi os.FileInfo) error {
+	if fi.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("key file %s has mode %04o: %w", path, fi.Mode().Perm(), ErrKeyFileMode)
+	}
+	return nil
+}
+
+// newProcessGroup makes cmd start a process group, so that the processes it
+// starts are killed along it
+func newProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group started by cmd
+func killProcessGroup(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
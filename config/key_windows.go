@@ -0,0 +1,18 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+)
+
+// checkKeyFile does not check the permissions on Windows, where they are
+// given by ACLs instead of the file mode
+func checkKeyFile(path string, fi os.FileInfo) error { return nil }
+
+// newProcessGroup does nothing on Windows, where only the command is killed
+func newProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
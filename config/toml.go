@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used by the configuration: tables,
+// and keys with string, integer and boolean values. fn is called with
+// every key, the table it belongs to and its value, a string, int64 or
+// bool.
+func parseTOML(r io.Reader, fn func(table []string, key string, v interface{}) error) error {
+	var table []string
+	seen := make(map[string]bool) // keys and tables defined
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			t, rest, err := parseTable(line)
+			if err == nil {
+				err = endOfLine(rest)
+			}
+			if err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+			name := "[" + strings.Join(t, ".")
+			if seen[name] {
+				return fmt.Errorf("line %d: table %s defined twice", n, strings.Join(t, "."))
+			}
+			seen[name] = true
+			table = t
+			continue
+		}
+
+		k, v, err := parseKeyValue(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", n, err)
+		}
+		name := strings.Join(append(append([]string(nil), table...), k), ".")
+		if seen[name] {
+			return fmt.Errorf("line %d: key %s defined twice", n, name)
+		}
+		seen[name] = true
+		if err := fn(table, k, v); err != nil {
+			return fmt.Errorf("line %d: %s", n, err)
+		}
+	}
+	return s.Err()
+}
+
+// parseTable parses the header of a table, as [a.b], and returns its keys
+// and the rest of the line
+func parseTable(line string) ([]string, string, error) {
+	var keys []string
+	rest := line[1:]
+	for {
+		k, r, err := parseKey(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, "", err
+		}
+		keys = append(keys, k)
+		rest = strings.TrimSpace(r)
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "]"):
+			return keys, rest[1:], nil
+		default:
+			return nil, "", fmt.Errorf("wrong table %s", line)
+		}
+	}
+}
+
+// parseKeyValue parses a line key = value
+func parseKeyValue(line string) (string, interface{}, error) {
+	k, rest, err := parseKey(line)
+	if err != nil {
+		return "", nil, err
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return "", nil, fmt.Errorf("= expected after key %s", k)
+	}
+	rest = strings.TrimSpace(rest[1:])
+
+	var v interface{}
+	switch {
+	case rest == "":
+		return "", nil, fmt.Errorf("value expected for key %s", k)
+	case rest[0] == '"' || rest[0] == '\'':
+		v, rest, err = parseString(rest)
+	case strings.HasPrefix(rest, "true"):
+		v, rest = true, rest[4:]
+	case strings.HasPrefix(rest, "false"):
+		v, rest = false, rest[5:]
+	default:
+		end := strings.IndexAny(rest, " \t#")
+		if end < 0 {
+			end = len(rest)
+		}
+		var i int64
+		i, err = strconv.ParseInt(strings.Replace(rest[:end], "_", "", -1), 10, 64)
+		if err != nil {
+			err = fmt.Errorf("unsupported value %s, use a string, integer or boolean", rest[:end])
+		}
+		v, rest = i, rest[end:]
+	}
+	if err == nil {
+		err = endOfLine(rest)
+	}
+	return k, v, err
+}
+
+// parseKey parses a bare or quoted key and returns the rest of the line
+func parseKey(s string) (string, string, error) {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		return parseString(s)
+	}
+
+	end := 0
+	for end < len(s) && isBareKeyChar(s[end]) {
+		end++
+	}
+	if end == 0 {
+		return "", "", fmt.Errorf("key expected at %q", s)
+	}
+	return s[:end], s[end:], nil
+}
+
+// parseString parses a basic "string" or a literal 'string' and returns
+// the rest of the line
+func parseString(s string) (string, string, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			if quote == '\'' {
+				return s[1:i], s[i+1:], nil
+			}
+			v, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("wrong string %s", s[:i+1])
+			}
+			return v, s[i+1:], nil
+		}
+	}
+	return "", "", errors.New("unterminated string")
+}
+
+// endOfLine checks only spaces and a comment follow
+func endOfLine(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != '#' {
+		return fmt.Errorf("unexpected %q", rest)
+	}
+	return nil
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func isBareKey(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isBareKeyChar(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// key returns s as a key, quoted unless it is bare
+func key(s string) string {
+	if isBareKey(s) {
+		return s
+	}
+	return quote(s)
+}
+
+// quote returns s as a basic string
+func quote(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,224 @@
+// Package cron parses cron expressions and computes the times they fire
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit i set when value i matches
+	// domAny and dowAny record when day of month or day of week are *. When
+	// both are restricted a day matches either of them, as in Vixie cron.
+	domAny, dowAny bool
+}
+
+// field describes the range and names of a field of an expression
+type field struct {
+	name     string
+	min, max int
+	names    []string // names of the values from min
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{
+		"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC",
+	}}
+	// 7 is Sunday as well as 0
+	dowField = field{name: "day of week", min: 0, max: 7, names: []string{
+		"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT",
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a standard cron expression of five fields: minute, hour, day
+// of month, month and day of week. Fields accept *, values, ranges a-b,
+// steps */n and a-b/n, lists separated by commas, and names for months and
+// days of week. The macros @yearly, @annually, @monthly, @weekly, @daily,
+// @midnight and @hourly are accepted as well.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	s := &Schedule{
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	for i, f := range []struct {
+		bits *uint64
+		field
+	}{
+		{&s.minute, minuteField},
+		{&s.hour, hourField},
+		{&s.dom, domField},
+		{&s.month, monthField},
+		{&s.dow, dowField},
+	} {
+		if *f.bits, err = f.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %s", expr, err)
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	return s, nil
+}
+
+// parse parses the list of ranges of the field
+func (f field) parse(list string) (uint64, error) {
+	var bits uint64
+	for _, r := range strings.Split(list, ",") {
+		b, err := f.parseRange(r)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseRange parses *, a value or a range with an optional step
+func (f field) parseRange(r string) (uint64, error) {
+	step := 1
+	if i := strings.Index(r, "/"); i >= 0 {
+		var err error
+		if step, err = strconv.Atoi(r[i+1:]); err != nil || step <= 0 {
+			return 0, fmt.Errorf("wrong step %q in %s", r[i+1:], f.name)
+		}
+		r = r[:i]
+	}
+
+	var from, to int
+	switch i := strings.Index(r, "-"); {
+	case r == "*":
+		from, to = f.min, f.max
+	case i >= 0:
+		var err error
+		if from, err = f.value(r[:i]); err != nil {
+			return 0, err
+		}
+		if to, err = f.value(r[i+1:]); err != nil {
+			return 0, err
+		}
+		if from > to {
+			return 0, fmt.Errorf("wrong range %q in %s", r, f.name)
+		}
+	default:
+		var err error
+		if from, err = f.value(r); err != nil {
+			return 0, err
+		}
+		to = from
+		if step > 1 { // a/n goes from a to the end
+			to = f.max
+		}
+	}
+
+	var bits uint64
+	for v := from; v <= to; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+// value parses a number or name of the field
+func (f field) value(s string) (int, error) {
+	for i, n := range f.names {
+		if strings.EqualFold(s, n) {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("wrong value %q in %s, want %d-%d", s, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time the schedule fires after t, in the location
+// of t. It returns the zero time when it does not fire in the next five
+// years, as for 0 0 30 2 *.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		case !s.dayMatches(t):
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		case !has(s.hour, t.Hour()):
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+		case !has(s.minute, t.Minute()):
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc))
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// forward returns next when it is after t. Otherwise next is a wall clock
+// time in a daylight saving gap which Go normalises backwards, as 2:00 to
+// 1:00 in America/New_York, and it returns the start of the hour after t.
+// Stepping on the wall clock, the hour repeated when clocks go back is
+// skipped, so the schedule fires once in it.
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Duration(60-t.Minute()) * time.Minute)
+}
+
+// NextN returns the next n times the schedule fires after t
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	var times []time.Time
+	for i := 0; i < n; i++ {
+		if t = s.Next(t); t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
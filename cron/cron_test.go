@@ -0,0 +1,221 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/cron"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2020, time.January, 15, 10, 30, 45, 0, time.UTC) // Wednesday
+	tt := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "* * * * *", want: time.Date(2020, time.January, 15, 10, 31, 0, 0, time.UTC)},
+		{expr: "0 3 * * *", want: time.Date(2020, time.January, 16, 3, 0, 0, 0, time.UTC)},
+		{expr: "30 10 * * *", want: time.Date(2020, time.January, 16, 10, 30, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", want: time.Date(2020, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{expr: "5-10/5 * * * *", want: time.Date(2020, time.January, 15, 11, 5, 0, 0, time.UTC)},
+		{expr: "20/20 * * * *", want: time.Date(2020, time.January, 15, 10, 40, 0, 0, time.UTC)},
+		{expr: "0 9,18 * * *", want: time.Date(2020, time.January, 15, 18, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", want: time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", want: time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * jun *", want: time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * * mon-fri", want: time.Date(2020, time.January, 15, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * SUN", want: time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", want: time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week when both are restricted
+		{expr: "0 0 20 * fri", want: time.Date(2020, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 16 * fri", want: time.Date(2020, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		// day of month and day of week when one is *
+		{expr: "0 0 */2 * fri", want: time.Date(2020, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{expr: "@hourly", want: time.Date(2020, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{expr: "@daily", want: time.Date(2020, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{expr: "@weekly", want: time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{expr: "@monthly", want: time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "@yearly", want: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			// given
+			s, err := cron.Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// when
+			got := s.Next(from)
+
+			// then
+			if !got.Equal(tc.want) {
+				t.Errorf("wrong next time want %s got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNext_Location(t *testing.T) {
+	// given
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip(err)
+	}
+	s, _ := cron.Parse("0 3 * * *")
+	from := time.Date(2020, time.January, 15, 10, 0, 0, 0, time.UTC)
+
+	// when
+	got := s.Next(from.In(madrid))
+
+	// then
+	want := time.Date(2020, time.January, 16, 2, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("wrong next time want %s got %s", want, got)
+	}
+}
+
+func TestNext_DaylightSaving(t *testing.T) {
+	// given
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skip(err)
+	}
+	s, _ := cron.Parse("30 2 * * *")
+	from := time.Date(2020, time.March, 28, 12, 0, 0, 0, madrid)
+
+	// when, 2:30 does not exist on March 29
+	got := s.Next(from)
+
+	// then
+	want := time.Date(2020, time.March, 30, 2, 30, 0, 0, madrid)
+	if !got.Equal(want) {
+		t.Errorf("wrong next time want %s got %s", want, got)
+	}
+}
+
+func TestNextN_DaylightSaving(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tt := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			name: "spring forward",
+			expr: "0 3 * * *",
+			from: time.Date(2027, time.March, 12, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2027, time.March, 13, 3, 0, 0, 0, newYork),
+				time.Date(2027, time.March, 14, 3, 0, 0, 0, newYork),
+				time.Date(2027, time.March, 15, 3, 0, 0, 0, newYork),
+			},
+		},
+		{
+			name: "spring forward in the gap",
+			expr: "30 2 * * *",
+			from: time.Date(2027, time.March, 13, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2027, time.March, 15, 2, 30, 0, 0, newYork),
+			},
+		},
+		{
+			name: "fall back",
+			expr: "30 1 * * *",
+			from: time.Date(2027, time.November, 6, 12, 0, 0, 0, newYork),
+			want: []time.Time{
+				time.Date(2027, time.November, 7, 5, 30, 0, 0, time.UTC), // 1:30 EDT
+				time.Date(2027, time.November, 8, 1, 30, 0, 0, newYork),
+			},
+		},
+		{
+			name: "fall back every half hour",
+			expr: "*/30 1-2 * * *",
+			from: time.Date(2027, time.November, 7, 4, 50, 0, 0, time.UTC).In(newYork), // 0:50 EDT
+			want: []time.Time{
+				time.Date(2027, time.November, 7, 5, 0, 0, 0, time.UTC),  // 1:00 EDT
+				time.Date(2027, time.November, 7, 5, 30, 0, 0, time.UTC), // 1:30 EDT
+				time.Date(2027, time.November, 7, 7, 0, 0, 0, time.UTC),  // 2:00 EST
+				time.Date(2027, time.November, 7, 7, 30, 0, 0, time.UTC), // 2:30 EST
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			s, err := cron.Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// when
+			got := s.NextN(tc.from, len(tc.want))
+
+			// then
+			if len(got) != len(tc.want) {
+				t.Fatalf("wrong number of times want %d got %d", len(tc.want), len(got))
+			}
+			for i := range tc.want {
+				if !got[i].Equal(tc.want[i]) {
+					t.Errorf("wrong time %d want %s got %s", i, tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNextN(t *testing.T) {
+	// given
+	s, _ := cron.Parse("0 */6 * * *")
+	from := time.Date(2020, time.January, 15, 10, 0, 0, 0, time.UTC)
+
+	// when
+	got := s.NextN(from, 3)
+
+	// then
+	want := []time.Time{
+		time.Date(2020, time.January, 15, 12, 0, 0, 0, time.UTC),
+		time.Date(2020, time.January, 15, 18, 0, 0, 0, time.UTC),
+		time.Date(2020, time.January, 16, 0, 0, 0, 0, time.UTC),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("wrong number of times want %d got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("wrong time %d want %s got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	tt := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"10-5 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every",
+	}
+
+	for _, expr := range tt {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := cron.Parse(expr); err == nil {
+				t.Errorf("error expected parsing %q", expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+// Package emulator implements in memory the Timehook API, to run the client
+// against it in integration tests and offline. Webhooks are really sent by a
+// scheduler after their delay.
+package emulator
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+// IDHeader is set to the webhook ID in the requests sent to the target
+const IDHeader = "X-Timehook-Id"
+
+// timeFormat is the format of the dates of the API
+const timeFormat = "2006-01-02T15:04:05-0700"
+
+// Server is an http.Handler emulating the Timehook API
+type Server struct {
+	keys       map[string]bool // any key is valid when empty
+	limit      int             // requests allowed per key and window, 0 for no limit
+	window     time.Duration
+	httpDoer   *http.Client
+	timeout    time.Duration
+	ctx        context.Context
+	stop       context.CancelFunc
+	deliveries sync.WaitGroup
+
+	mu       sync.Mutex
+	webhooks map[string]*webhook
+	seq      int
+	requests map[string]*counter // requests by key in the current window
+}
+
+// webhook is a webhook registered in the emulator
+type webhook struct {
+	key    string
+	url    string
+	method string
+	header http.Header
+	body   []byte
+	seq    int // order of registration
+	timer  *time.Timer
+	state  State
+}
+
+// State is the state of a webhook as returned by GET /states/{id}
+type State struct {
+	ID              string          `json:"id"`
+	RegisteredAt    string          `json:"registeredAt,omitempty"`
+	ScheduledAt     string          `json:"scheduledAt,omitempty"`
+	AwaitingClockAt string          `json:"awaitingClockAt,omitempty"`
+	SendingHttpAt   string          `json:"sendingHttpAt,omitempty"`
+	SucceededAt     string          `json:"succeededAt,omitempty"`
+	FailedAt        string          `json:"failedAt,omitempty"`
+	Status          timehook.Status `json:"status"`
+}
+
+// counter counts the requests of a key since start
+type counter struct {
+	start time.Time
+	n     int
+}
+
+// Option configures optional parameters of the server
+type Option func(s *Server)
+
+// WithKeys sets the API keys accepted, any key is accepted otherwise
+func WithKeys(keys ...string) Option {
+	return func(s *Server) {
+		for _, k := range keys {
+			s.keys[k] = true
+		}
+	}
+}
+
+// WithRateLimit allows n requests per key every window. Requests over the
+// limit are responded with 429 Too Many Requests and Retry-After.
+func WithRateLimit(n int, window time.Duration) Option {
+	return func(s *Server) {
+		s.limit = n
+		s.window = window
+	}
+}
+
+// WithTimeout sets how long the target has to respond before the webhook
+// times out, 10 seconds by default
+func WithTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.timeout = d
+	}
+}
+
+// WithHTTPClient sets the HTTP client sending the webhooks
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Server) {
+		s.httpDoer = c
+	}
+}
+
+// New returns a new emulator configured with the options given
+func New(opts ...Option) *Server {
+	ctx, stop := context.WithCancel(context.Background())
+	s := &Server{
+		keys:     make(map[string]bool),
+		httpDoer: http.DefaultClient,
+		timeout:  10 * time.Second,
+		ctx:      ctx,
+		stop:     stop,
+		webhooks: make(map[string]*webhook),
+		requests: make(map[string]*counter),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Close stops the scheduler, so webhooks pending are not sent, and waits
+// for the webhooks being sent
+func (s *Server) Close() {
+	s.stop()
+	s.mu.Lock()
+	for _, wh := range s.webhooks {
+		if wh.timer != nil && wh.timer.Stop() {
+			s.deliveries.Done()
+		}
+	}
+	s.mu.Unlock()
+	s.deliveries.Wait()
+}
+
+// ServeHTTP serves the Timehook API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := s.authenticate(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+	if retry, ok := s.allow(key); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	path := strings.TrimRight(r.URL.Path, "/")
+	switch {
+	case path == "/webhooks" && r.Method == http.MethodPost:
+		s.register(w, r, key)
+	case path == "/webhooks" && r.Method == http.MethodGet:
+		s.list(w, key)
+	case strings.HasPrefix(path, "/webhooks/") && r.Method == http.MethodDelete:
+		s.cancel(w, key, strings.TrimPrefix(path, "/webhooks/"))
+	case strings.HasPrefix(path, "/states/") && r.Method == http.MethodGet:
+		s.state(w, key, strings.TrimPrefix(path, "/states/"))
+	case path == "/webhooks" || strings.HasPrefix(path, "/webhooks/") || strings.HasPrefix(path, "/states/"):
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// authenticate returns the API key of the request and if it is valid
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	key := strings.TrimPrefix(auth, "Bearer ")
+	return key, key != "" && (len(s.keys) == 0 || s.keys[key])
+}
+
+// allow counts a request of the key and returns if it is allowed or, when
+// it is over the limit, how long until the next window
+func (s *Server) allow(key string) (time.Duration, bool) {
+	if s.limit <= 0 {
+		return 0, true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	c, ok := s.requests[key]
+	if !ok || now.Sub(c.start) >= s.window {
+		c = &counter{start: now}
+		s.requests[key] = c
+	}
+	if c.n >= s.limit {
+		return c.start.Add(s.window).Sub(now), false
+	}
+	c.n++
+	return 0, true
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request, key string) {
+	url := r.Header.Get("X-Webhook")
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		writeError(w, http.StatusBadRequest, "X-Webhook must be an HTTP URL")
+		return
+	}
+	sec, err := strconv.Atoi(r.Header.Get("X-Seconds"))
+	if err != nil || sec < 0 {
+		writeError(w, http.StatusBadRequest, "X-Seconds must be a number of seconds")
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "can not read body")
+		return
+	}
+
+	wh := &webhook{
+		key:    key,
+		url:    url,
+		method: http.MethodPost,
+		header: http.Header{},
+		body:   body,
+	}
+	if m := r.Header.Get("X-Method"); m != "" {
+		wh.method = strings.ToUpper(m)
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		wh.header.Set("Content-Type", ct)
+	}
+	for name, values := range r.Header {
+		if strings.HasPrefix(name, timehook.HeaderPrefix) && len(name) > len(timehook.HeaderPrefix) {
+			wh.header[http.CanonicalHeaderKey(name[len(timehook.HeaderPrefix):])] = values
+		}
+	}
+
+	now := time.Now()
+	delay := time.Duration(sec) * time.Second
+	s.mu.Lock()
+	ID := newID()
+	s.seq++
+	wh.seq = s.seq
+	wh.state = State{
+		ID:           ID,
+		RegisteredAt: now.Format(timeFormat),
+		ScheduledAt:  now.Add(delay).Format(timeFormat),
+		Status:       timehook.StatusRegistered,
+	}
+	s.webhooks[ID] = wh
+	s.mu.Unlock()
+
+	go s.schedule(ID, delay)
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"_links": map[string]string{"self": "/webhooks/" + ID, "states": "/states/" + ID},
+		"id":     ID,
+	})
+}
+
+// schedule makes the webhook await the clock and sends it after delay
+func (s *Server) schedule(ID string, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wh, ok := s.webhooks[ID]
+	if !ok || s.ctx.Err() != nil {
+		return
+	}
+
+	wh.state.AwaitingClockAt = time.Now().Format(timeFormat)
+	wh.state.Status = timehook.StatusAwaitingClock
+	s.deliveries.Add(1)
+	wh.timer = time.AfterFunc(delay, func() {
+		defer s.deliveries.Done()
+		s.send(ID)
+	})
+}
+
+// send sends the webhook to its target and records how it finished
+func (s *Server) send(ID string) {
+	s.mu.Lock()
+	wh, ok := s.webhooks[ID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	wh.state.SendingHttpAt = time.Now().Format(timeFormat)
+	wh.state.Status = timehook.StatusSendingHTTP
+	s.mu.Unlock()
+
+	status := timehook.StatusSucceeded
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	defer cancel()
+	req, err := http.NewRequest(wh.method, wh.url, bytes.NewReader(wh.body))
+	if err == nil {
+		req = req.WithContext(ctx)
+		for name, values := range wh.header {
+			req.Header[name] = values
+		}
+		req.Header.Set(IDHeader, ID)
+
+		var resp *http.Response
+		resp, err = s.httpDoer.Do(req)
+		if err == nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				status = timehook.StatusFailed
+			}
+		}
+	}
+	switch {
+	case err != nil && ctx.Err() == context.DeadlineExceeded:
+		status = timehook.StatusTimeout
+	case err != nil:
+		status = timehook.StatusFailed
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at := time.Now().Format(timeFormat)
+	if status == timehook.StatusSucceeded {
+		wh.state.SucceededAt = at
+	} else {
+		wh.state.FailedAt = at
+	}
+	wh.state.Status = status
+}
+
+func (s *Server) state(w http.ResponseWriter, key, ID string) {
+	s.mu.Lock()
+	wh, ok := s.webhooks[ID]
+	var st State
+	if ok {
+		st = wh.state
+	}
+	s.mu.Unlock()
+
+	if !ok || wh.key != key {
+		writeError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// cancel removes the webhook unless it has been sent already
+func (s *Server) cancel(w http.ResponseWriter, key, ID string) {
+	s.mu.Lock()
+	wh, ok := s.webhooks[ID]
+	if !ok || wh.key != key {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	if wh.state.Status != timehook.StatusRegistered && wh.state.Status != timehook.StatusAwaitingClock {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "webhook already sent")
+		return
+	}
+	if wh.timer != nil && wh.timer.Stop() {
+		s.deliveries.Done()
+	}
+	delete(s.webhooks, ID)
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// list returns the state of the webhooks of the key by registration
+func (s *Server) list(w http.ResponseWriter, key string) {
+	s.mu.Lock()
+	var whs []*webhook
+	for _, wh := range s.webhooks {
+		if wh.key == key {
+			whs = append(whs, wh)
+		}
+	}
+	sort.Slice(whs, func(i, j int) bool { return whs[i].seq < whs[j].seq })
+	states := make([]State, len(whs))
+	for i, wh := range whs {
+		states[i] = wh.state
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, states)
+}
+
+// States returns the state of every webhook registered, e.g. to check them
+// in tests
+func (s *Server) States() map[string]State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make(map[string]State, len(s.webhooks))
+	for ID, wh := range s.webhooks {
+		states[ID] = wh.state
+	}
+	return states
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"message": message})
+}
+
+// newID returns a random UUID
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,228 @@
+package emulator_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/emulator"
+	"github.com/timehook/cli-client/timehook"
+)
+
+// start returns a client of a new emulator configured with the options
+// given and a function to stop it
+func start(key string, opts ...emulator.Option) (*timehook.Client, *emulator.Server, func()) {
+	e := emulator.New(opts...)
+	srv := httptest.NewServer(e)
+	client := timehook.New(key, http.DefaultClient, timehook.WithBaseURL(srv.URL))
+	return client, e, func() {
+		srv.Close()
+		e.Close()
+	}
+}
+
+func TestServer_Succeeded(t *testing.T) {
+	// given
+	received := make(chan *http.Request, 1)
+	var body string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		received <- r
+	}))
+	defer target.Close()
+	client, _, stop := start("api-key", emulator.WithKeys("api-key"))
+	defer stop()
+
+	// when
+	r := &timehook.RegisterRequest{
+		URL:     target.URL,
+		Body:    strings.NewReader(`{"foo" : "bar"}`),
+		Method:  "PUT",
+		Headers: http.Header{"X-Request-Id": {"42"}},
+	}
+	proc := client.RegisterAndPollContext(context.Background(), r, 10*time.Millisecond)
+	var kinds []timehook.EventKind
+	for e := range proc.C {
+		kinds = append(kinds, e.Kind)
+	}
+	res, err := proc.Result()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Outcome != timehook.OutcomeSucceeded {
+		t.Errorf("wrong outcome want %s got %s", timehook.OutcomeSucceeded, res.Outcome)
+	}
+	if kinds[len(kinds)-2] != timehook.EventSending {
+		t.Errorf("sending event expected before finishing, got %v", kinds)
+	}
+	for _, at := range []time.Time{res.State.RegisteredTime(), res.State.ScheduledTime(), res.State.SendingHTTPTime(), res.State.SucceededTime()} {
+		if at.IsZero() {
+			t.Errorf("date missing in state %+v", res.State)
+		}
+	}
+
+	req := <-received
+	if req.Method != "PUT" {
+		t.Errorf("wrong method want %s got %s", "PUT", req.Method)
+	}
+	if body != `{"foo" : "bar"}` {
+		t.Errorf("wrong body want %s got %s", `{"foo" : "bar"}`, body)
+	}
+	if req.Header.Get("X-Request-Id") != "42" {
+		t.Errorf("wrong header X-Request-Id want %s got %s", "42", req.Header.Get("X-Request-Id"))
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("wrong content type want %s got %s", "application/json", req.Header.Get("Content-Type"))
+	}
+	if req.Header.Get(emulator.IDHeader) != res.WebhookID {
+		t.Errorf("wrong header %s want %s got %s", emulator.IDHeader, res.WebhookID, req.Header.Get(emulator.IDHeader))
+	}
+}
+
+func TestServer_Outcomes(t *testing.T) {
+	tt := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    timehook.Outcome
+	}{
+		{
+			name:    "failed",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			want:    timehook.OutcomeFailed,
+		},
+		{
+			name:    "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) { time.Sleep(200 * time.Millisecond) },
+			want:    timehook.OutcomeTimeout,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			target := httptest.NewServer(tc.handler)
+			defer target.Close()
+			client, _, stop := start("api-key", emulator.WithTimeout(50*time.Millisecond))
+			defer stop()
+
+			// when
+			r := &timehook.RegisterRequest{URL: target.URL}
+			res, err := client.RegisterAndPollContext(context.Background(), r, 10*time.Millisecond).Result()
+
+			// then
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if res.Outcome != tc.want {
+				t.Errorf("wrong outcome want %s got %s", tc.want, res.Outcome)
+			}
+		})
+	}
+}
+
+func TestServer_Delay(t *testing.T) {
+	// given
+	client, e, stop := start("api-key")
+	defer stop()
+
+	// when
+	rr, err := client.Register(context.Background(), &timehook.RegisterRequest{URL: "http://127.0.0.1:1", Delay: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	sr, err := client.State(context.Background(), rr.ID)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if sr.Status != timehook.StatusAwaitingClock {
+		t.Errorf("wrong status want %s got %s", timehook.StatusAwaitingClock, sr.Status)
+	}
+	if d := sr.ScheduledTime().Sub(sr.RegisteredTime()); d != time.Hour {
+		t.Errorf("wrong schedule want %s got %s", time.Hour, d)
+	}
+	if len(e.States()) != 1 {
+		t.Errorf("wrong number of webhooks want %d got %d", 1, len(e.States()))
+	}
+}
+
+func TestServer_CancelAndList(t *testing.T) {
+	// given
+	client, _, stop := start("api-key")
+	defer stop()
+	var IDs []string
+	for i := 0; i < 3; i++ {
+		rr, err := client.Register(context.Background(), &timehook.RegisterRequest{URL: "http://127.0.0.1:1", Delay: time.Hour})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		IDs = append(IDs, rr.ID)
+	}
+
+	// when
+	err := client.Cancel(context.Background(), IDs[1])
+	srs, listErr := client.List(context.Background())
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if listErr != nil {
+		t.Fatalf("unexpected error: %s", listErr)
+	}
+	if len(srs) != 2 || srs[0].ID != IDs[0] || srs[1].ID != IDs[2] {
+		t.Errorf("wrong webhooks listed want %s and %s got %+v", IDs[0], IDs[2], srs)
+	}
+	if _, err := client.State(context.Background(), IDs[1]); err == nil {
+		t.Error("error expected querying a webhook cancelled")
+	}
+}
+
+func TestServer_Unauthorized(t *testing.T) {
+	// given
+	client, _, stop := start("wrong-key", emulator.WithKeys("api-key"))
+	defer stop()
+
+	// when
+	_, err := client.Register(context.Background(), &timehook.RegisterRequest{URL: "http://127.0.0.1:1"})
+
+	// then
+	if !errors.Is(err, timehook.ErrUnauthorized) {
+		t.Errorf("wrong error want %s got %v", timehook.ErrUnauthorized, err)
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	// given
+	e := emulator.New(emulator.WithRateLimit(1, time.Minute))
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	defer e.Close()
+	client := timehook.New("api-key", http.DefaultClient, timehook.WithBaseURL(srv.URL), timehook.WithRetryPolicy(timehook.RetryPolicy{MaxAttempts: 1}))
+
+	// when
+	_, err1 := client.Register(context.Background(), &timehook.RegisterRequest{URL: "http://127.0.0.1:1", Delay: time.Hour})
+	_, err2 := client.Register(context.Background(), &timehook.RegisterRequest{URL: "http://127.0.0.1:1", Delay: time.Hour})
+
+	// then
+	if err1 != nil {
+		t.Fatalf("unexpected error: %s", err1)
+	}
+	var apiErr *timehook.APIError
+	if !errors.As(err2, &apiErr) || !apiErr.Is(timehook.ErrTooManyRequests) {
+		t.Fatalf("wrong error want %s got %v", timehook.ErrTooManyRequests, err2)
+	}
+	if apiErr.Header.Get("Retry-After") == "" {
+		t.Error("Retry-After header expected")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sync"
 )
 
 const (
@@ -57,13 +58,17 @@ const (
 )
 
 type httpDoer struct {
+	mu    sync.Mutex
 	stack []interface{}
 	spies []*http.Request
 }
 
 // Do returns the next response in the stack, an *http.Response or an error.
-// When the stack exhausted it panics
+// When the stack exhausted it panics. It is safe to call it from several
+// goroutines.
 func (c *httpDoer) Do(req *http.Request) (res *http.Response, e error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if len(c.stack) == 0 {
 		panic("no more responses in the stack")
 	}
@@ -86,6 +91,8 @@ func (c *httpDoer) Do(req *http.Request) (res *http.Response, e error) {
 
 // Spies returns all the http.Request given as params
 func (c *httpDoer) Spies() []*http.Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.spies
 }
 
@@ -96,6 +103,15 @@ func RegisteredSuccess() *http.Response {
 	return resp
 }
 
+// RegisteredWithStatesLink returns a registration response with the link
+// to the states given
+func RegisteredWithStatesLink(link string) *http.Response {
+	resp := makeResponse(fmt.Sprintf(`{"_links": {"self": "/webhooks", "states": %q}, "id": "9e9480a4-271b-4708-993a-064509457a23"}`, link))
+	resp.Status = "201 Created"
+	resp.StatusCode = 201
+	return resp
+}
+
 func StateRegistered() *http.Response {
 	return makeResponse(stateRegistered)
 }
@@ -116,6 +132,17 @@ func StateFailed() *http.Response {
 	return makeResponse(stateFailed)
 }
 
+func Cancelled() *http.Response {
+	resp := makeResponse("")
+	resp.StatusCode = 204
+	resp.Status = "204 No Content"
+	return resp
+}
+
+func List() *http.Response {
+	return makeResponse("[" + stateSucceeded + ", " + stateAwaitingClock + "]")
+}
+
 func Unauthorized() *http.Response {
 	resp := makeResponse("")
 	resp.StatusCode = 401
@@ -130,6 +157,20 @@ func TooManyRequest429() *http.Response {
 	return resp
 }
 
+func NotFound() *http.Response {
+	resp := makeResponse(`{"message": "webhook not found"}`)
+	resp.StatusCode = 404
+	resp.Status = "404 Not Found"
+	return resp
+}
+
+func InternalServerError() *http.Response {
+	resp := makeResponse("upstream connect error")
+	resp.StatusCode = 500
+	resp.Status = "500 Internal Server Error"
+	return resp
+}
+
 func makeResponse(body string) *http.Response {
 	return &http.Response{
 		Status:     "200 OK",
@@ -146,5 +187,5 @@ func makeResponse(body string) *http.Response {
 // responses in the stack and recording http.Request received.
 // Stack should be *http.Responses or error
 func HTTPClient(stack []interface{}) *httpDoer {
-	return &httpDoer{stack, make([]*http.Request, 0)}
+	return &httpDoer{stack: stack, spies: make([]*http.Request, 0)}
 }
@@ -0,0 +1,204 @@
+// Package receiver records the requests received by a local HTTP endpoint,
+// to check the webhooks Timehook sends to it
+package receiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timehook/cli-client/emulator"
+)
+
+// IDHeader carries the ID of the webhook in the requests sent by the
+// Timehook API emulator
+const IDHeader = emulator.IDHeader
+
+// maxBody bounds the body recorded of every request
+const maxBody = 10 << 20
+
+// Request is a request received
+type Request struct {
+	Method string
+	// URL is the path and query of the request
+	URL    string
+	Header http.Header
+	Body   []byte
+	// At is when the request arrived
+	At time.Time
+	// WebhookID is the ID of the webhook, when the sender tells it in IDHeader
+	WebhookID string
+}
+
+// Recorder is an http.Handler recording every request received and
+// responding them with the same status code
+type Recorder struct {
+	status int
+	notify func(Request)
+
+	mu       sync.Mutex
+	requests []Request
+	arrived  chan struct{} // closed when a request arrives
+}
+
+// Option configures optional parameters of the recorder
+type Option func(r *Recorder)
+
+// WithStatus sets the status code of the responses, 200 OK by default
+func WithStatus(code int) Option {
+	return func(r *Recorder) {
+		r.status = code
+	}
+}
+
+// WithNotify sets a function called with every request as it arrives, e.g.
+// to print it
+func WithNotify(fn func(Request)) Option {
+	return func(r *Recorder) {
+		r.notify = fn
+	}
+}
+
+// New returns a new recorder configured with the options given
+func New(opts ...Option) *Recorder {
+	r := &Recorder{status: http.StatusOK, arrived: make(chan struct{})}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// ServeHTTP records the request and responds it
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		http.Error(w, "can not read body", http.StatusRequestEntityTooLarge)
+		return
+	}
+	req := Request{
+		Method:    r.Method,
+		URL:       r.URL.RequestURI(),
+		Header:    r.Header,
+		Body:      body,
+		At:        time.Now(),
+		WebhookID: r.Header.Get(IDHeader),
+	}
+
+	rec.mu.Lock()
+	rec.requests = append(rec.requests, req)
+	close(rec.arrived)
+	rec.arrived = make(chan struct{})
+	rec.mu.Unlock()
+
+	if rec.notify != nil {
+		rec.notify(req)
+	}
+	w.WriteHeader(rec.status)
+}
+
+// Requests returns the requests received so far
+func (rec *Recorder) Requests() []Request {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]Request(nil), rec.requests...)
+}
+
+// Wait waits until a request matching arrives, or has arrived already, and
+// returns the first one. It returns the error of ctx when it is done before.
+func (rec *Recorder) Wait(ctx context.Context, match func(Request) bool) (Request, error) {
+	for {
+		rec.mu.Lock()
+		for _, r := range rec.requests {
+			if match(r) {
+				rec.mu.Unlock()
+				return r, nil
+			}
+		}
+		arrived := rec.arrived
+		rec.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-ctx.Done():
+			return Request{}, ctx.Err()
+		}
+	}
+}
+
+// MatchID matches the requests of the webhook identified by ID in IDHeader
+func MatchID(ID string) func(Request) bool {
+	return func(r Request) bool {
+		return r.WebhookID == ID
+	}
+}
+
+// Expectation describes the request a webhook is expected to send
+type Expectation struct {
+	// ID is the ID of the webhook
+	ID string
+	// AnyRequest makes any request which does not tell its webhook match,
+	// to check its body rather than match on it
+	AnyRequest bool
+	// Body is the body expected, any when nil. JSON bodies are compared by
+	// value, so spaces and the order of the keys do not matter.
+	Body []byte
+	// ScheduledAt is when the webhook is sent, not checked when zero
+	ScheduledAt time.Time
+	// Tolerance is the time the request may arrive before or after
+	// ScheduledAt
+	Tolerance time.Duration
+}
+
+// Deadline returns the last time the request is expected to arrive
+func (e Expectation) Deadline() time.Time {
+	return e.ScheduledAt.Add(e.Tolerance)
+}
+
+// Match returns if the request is the one of the webhook expected: the one
+// with its ID in IDHeader or, as the Timehook API does not set IDHeader,
+// any one without IDHeader with AnyRequest or one with the body expected
+func (e Expectation) Match(r Request) bool {
+	switch {
+	case r.WebhookID != "":
+		return r.WebhookID == e.ID
+	case e.AnyRequest:
+		return true
+	}
+	return e.Body != nil && equalBodies(e.Body, r.Body)
+}
+
+// Check returns an error describing how the request does not meet the
+// expectation, if it does not
+func (e Expectation) Check(r Request) error {
+	var problems []string
+	if e.Body != nil && !equalBodies(e.Body, r.Body) {
+		problems = append(problems, fmt.Sprintf("body %q, want %q", r.Body, e.Body))
+	}
+	if !e.ScheduledAt.IsZero() {
+		d := r.At.Sub(e.ScheduledAt)
+		if d < -e.Tolerance || d > e.Tolerance {
+			problems = append(problems, fmt.Sprintf("arrived %s from %s, tolerance %s", d.Round(time.Millisecond), e.ScheduledAt.Format(time.RFC3339), e.Tolerance))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("unexpected request: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// equalBodies returns if the bodies are equal, by value when both are JSON
+func equalBodies(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) == nil && json.Unmarshal(b, &vb) == nil {
+		return reflect.DeepEqual(va, vb)
+	}
+	return bytes.Equal(a, b)
+}
@@ -0,0 +1,209 @@
+package receiver_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/receiver"
+)
+
+func TestRecorder(t *testing.T) {
+	// given
+	var notified []receiver.Request
+	rec := receiver.New(receiver.WithStatus(http.StatusAccepted), receiver.WithNotify(func(r receiver.Request) {
+		notified = append(notified, r)
+	}))
+	req := httptest.NewRequest("PUT", "/hook?x=1", strings.NewReader(`{"foo" : "bar"}`))
+	req.Header.Set(receiver.IDHeader, "abc")
+	w := httptest.NewRecorder()
+
+	// when
+	rec.ServeHTTP(w, req)
+
+	// then
+	if w.Code != http.StatusAccepted {
+		t.Errorf("wrong status code want %d got %d", http.StatusAccepted, w.Code)
+	}
+	reqs := rec.Requests()
+	if len(reqs) != 1 || len(notified) != 1 {
+		t.Fatalf("wrong number of requests want %d got %d recorded and %d notified", 1, len(reqs), len(notified))
+	}
+	got := reqs[0]
+	if got.Method != "PUT" || got.URL != "/hook?x=1" || string(got.Body) != `{"foo" : "bar"}` || got.WebhookID != "abc" {
+		t.Errorf("wrong request recorded %+v", got)
+	}
+	if got.At.IsZero() {
+		t.Error("arrival time expected")
+	}
+}
+
+func TestRecorder_Wait(t *testing.T) {
+	// given
+	rec := receiver.New()
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	// when
+	go func() {
+		for _, ID := range []string{"other", "abc"} {
+			req, _ := http.NewRequest("POST", srv.URL, nil)
+			req.Header.Set(receiver.IDHeader, ID)
+			http.DefaultClient.Do(req)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, err := rec.Wait(ctx, receiver.MatchID("abc"))
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.WebhookID != "abc" {
+		t.Errorf("wrong request want %s got %s", "abc", got.WebhookID)
+	}
+}
+
+func TestRecorder_WaitTimeout(t *testing.T) {
+	// given
+	rec := receiver.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// when
+	_, err := rec.Wait(ctx, receiver.MatchID("abc"))
+
+	// then
+	if err != context.DeadlineExceeded {
+		t.Errorf("wrong error want %s got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestExpectation_Check(t *testing.T) {
+	scheduledAt := time.Date(2020, time.January, 15, 10, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name    string
+		given   receiver.Expectation
+		request receiver.Request
+		ok      bool
+	}{
+		{
+			name:    "any",
+			request: receiver.Request{Body: []byte("hello"), At: scheduledAt},
+			ok:      true,
+		},
+		{
+			name:    "same JSON body",
+			given:   receiver.Expectation{Body: []byte(`{"a": 1, "b": [true]}`)},
+			request: receiver.Request{Body: []byte(`{"b":[true],"a":1}`)},
+			ok:      true,
+		},
+		{
+			name:    "other JSON body",
+			given:   receiver.Expectation{Body: []byte(`{"a": 1}`)},
+			request: receiver.Request{Body: []byte(`{"a": 2}`)},
+		},
+		{
+			name:    "other text body",
+			given:   receiver.Expectation{Body: []byte("hello")},
+			request: receiver.Request{Body: []byte("hello ")},
+		},
+		{
+			name:    "in time",
+			given:   receiver.Expectation{ScheduledAt: scheduledAt, Tolerance: 2 * time.Second},
+			request: receiver.Request{At: scheduledAt.Add(1500 * time.Millisecond)},
+			ok:      true,
+		},
+		{
+			name:    "late",
+			given:   receiver.Expectation{ScheduledAt: scheduledAt, Tolerance: 2 * time.Second},
+			request: receiver.Request{At: scheduledAt.Add(3 * time.Second)},
+		},
+		{
+			name:    "early",
+			given:   receiver.Expectation{ScheduledAt: scheduledAt, Tolerance: 2 * time.Second},
+			request: receiver.Request{At: scheduledAt.Add(-3 * time.Second)},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			err := tc.given.Check(tc.request)
+
+			// then
+			if tc.ok && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Error("error expected")
+			}
+		})
+	}
+}
+
+func TestRecorder_WaitWithoutID(t *testing.T) {
+	tt := []struct {
+		name  string
+		given receiver.Expectation
+		want  string
+	}{
+		{
+			name:  "by body",
+			given: receiver.Expectation{ID: "abc", Body: []byte(`{"n": 2}`)},
+			want:  `{"n":2}`,
+		},
+		{
+			name:  "any",
+			given: receiver.Expectation{ID: "abc", Body: []byte(`{"n": 2}`), AnyRequest: true},
+			want:  `{"n":1}`,
+		},
+		{
+			name:  "by ID",
+			given: receiver.Expectation{ID: "abc"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			rec := receiver.New()
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+			for _, body := range []string{`{"n":1}`, `{"n":2}`} {
+				resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				resp.Body.Close()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			// when
+			got, err := rec.Wait(ctx, tc.given.Match)
+			_, errID := rec.Wait(ctx, receiver.MatchID("abc"))
+
+			// then
+			if errID != context.DeadlineExceeded {
+				t.Errorf("wrong error matching by ID want %s got %v", context.DeadlineExceeded, errID)
+			}
+			if tc.want == "" {
+				if err != context.DeadlineExceeded {
+					t.Errorf("wrong error want %s got %v", context.DeadlineExceeded, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(got.Body) != tc.want {
+				t.Errorf("wrong request want %s got %s", tc.want, got.Body)
+			}
+		})
+	}
+}
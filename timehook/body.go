@@ -0,0 +1,82 @@
+package timehook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// JSONError is returned when a webhook body is not a valid JSON document. It
+// locates the error in the body.
+type JSONError struct {
+	Line   int   // line of the error, starting at 1
+	Column int   // column of the error in bytes, starting at 1
+	Offset int64 // bytes read until the error
+	Err    error
+}
+
+func (e *JSONError) Error() string {
+	return fmt.Sprintf("invalid JSON body at line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *JSONError) Unwrap() error { return e.Err }
+
+// IsJSON returns if the media type is JSON, as application/json or any
+// structured syntax suffix +json is
+func IsJSON(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (t == "application/json" || strings.HasSuffix(t, "+json"))
+}
+
+// ValidateJSON returns a *JSONError when b is not a single valid JSON
+// document
+func ValidateJSON(b []byte) error {
+	_, err := CompactJSON(b)
+	return err
+}
+
+// CompactJSON returns the JSON document b without insignificant spaces or a
+// *JSONError when it is not valid
+func CompactJSON(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return nil, newJSONError(b, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// IndentJSON returns the JSON document b indented with two spaces or a
+// *JSONError when it is not valid
+func IndentJSON(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return nil, newJSONError(b, err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// newJSONError locates err, returned parsing b, in b
+func newJSONError(b []byte, err error) error {
+	serr, ok := err.(*json.SyntaxError)
+	if !ok {
+		return err
+	}
+
+	e := &JSONError{Line: 1, Column: 1, Offset: serr.Offset, Err: err}
+	end := serr.Offset - 1 // the offending byte
+	if end < 0 {
+		end = 0
+	}
+	for _, c := range b[:end] {
+		if c == '\n' {
+			e.Line++
+			e.Column = 1
+		} else {
+			e.Column++
+		}
+	}
+	return e
+}
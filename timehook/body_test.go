@@ -0,0 +1,85 @@
+package timehook_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestIsJSON(t *testing.T) {
+	tt := []struct {
+		given string
+		want  bool
+	}{
+		{given: "application/json", want: true},
+		{given: "application/json; charset=utf-8", want: true},
+		{given: "application/problem+json", want: true},
+		{given: "text/plain", want: false},
+		{given: "", want: false},
+	}
+	for _, v := range tt {
+		if got := timehook.IsJSON(v.given); got != v.want {
+			t.Errorf("wrong IsJSON(%q) want %t got %t", v.given, v.want, got)
+		}
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	tt := []struct {
+		name       string
+		given      string
+		wantErr    bool
+		wantLine   int
+		wantColumn int
+	}{
+		{name: "valid object", given: `{"foo" : "bar"}`},
+		{name: "valid with trailing new line", given: "[1, 2]\n"},
+		{name: "missing quote", given: "{\n  \"foo\": bar\n}", wantErr: true, wantLine: 2, wantColumn: 10},
+		{name: "trailing comma", given: `{"foo": 1,}`, wantErr: true, wantLine: 1, wantColumn: 11},
+		{name: "truncated", given: "{\"foo\":\n", wantErr: true, wantLine: 1, wantColumn: 8},
+		{name: "two documents", given: `{} {}`, wantErr: true, wantLine: 1, wantColumn: 4},
+		{name: "empty", given: ``, wantErr: true, wantLine: 1, wantColumn: 1},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			err := timehook.ValidateJSON([]byte(v.given))
+			if !v.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+
+			var jsonErr *timehook.JSONError
+			if !errors.As(err, &jsonErr) {
+				t.Fatalf("wrong error type want *timehook.JSONError got %T", err)
+			}
+			if jsonErr.Line != v.wantLine || jsonErr.Column != v.wantColumn {
+				t.Errorf("wrong position want %d:%d got %d:%d (%s)", v.wantLine, v.wantColumn, jsonErr.Line, jsonErr.Column, err)
+			}
+		})
+	}
+}
+
+func TestCompactJSON(t *testing.T) {
+	got, err := timehook.CompactJSON([]byte("{\n  \"foo\" : \"bar\",\n  \"n\": [1, 2]\n}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(got) != `{"foo":"bar","n":[1,2]}` {
+		t.Errorf("wrong compact JSON want %s got %s", `{"foo":"bar","n":[1,2]}`, got)
+	}
+}
+
+func TestIndentJSON(t *testing.T) {
+	got, err := timehook.IndentJSON([]byte(`{"foo":"bar","n":[1]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := "{\n  \"foo\": \"bar\",\n  \"n\": [\n    1\n  ]\n}\n"
+	if string(got) != want {
+		t.Errorf("wrong indented JSON want %q got %q", want, got)
+	}
+}
@@ -2,20 +2,20 @@
 package timehook
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 )
 
-const (
-	registerURL = "https://api.timehook.io/webhooks"
-	stateURL    = "https://api.timehook.io/states"
-)
+// DefaultBaseURL is the base URL of the Timehook API used when no other is
+// given with WithBaseURL
+const DefaultBaseURL = "https://api.timehook.io"
 
 // HTTPDoer is the interface that wraps the execution of send HTTP requests to
 // API server and return HTTP responses
@@ -25,7 +25,14 @@ type HTTPDoer interface {
 
 // RegisterResponse represents the body response when register a webhook
 type RegisterResponse struct {
-	ID string `json:"id"`
+	Links Links  `json:"_links"`
+	ID    string `json:"id"`
+}
+
+// Links represents the HAL links returned by the API along with a resource
+type Links struct {
+	Self   string `json:"self"`
+	States string `json:"states"`
 }
 
 // StateResponse represents the body response when query the state of a webhook
@@ -37,84 +44,171 @@ type StateResponse struct {
 	FailedAt        string `json:"failedAt"`
 	SucceededAt     string `json:"succeededAt"`
 	ScheduledAt     string `json:"scheduledAt"`
-	Status          string `json:"status"`
+	Status          Status `json:"status"`
 }
 
-var (
-	ErrTooManyRequests = errors.New("server responses 429 too many request")
-	ErrUnauthorized    = errors.New("server responses 401 unauthorized request")
-)
+// API is the interface that wraps the functions allowed to talk with the
+// Timehook API. It is implemented by Client and allows to mock it.
+type API interface {
+	Register(ctx context.Context, r *RegisterRequest) (*RegisterResponse, error)
+	State(ctx context.Context, ID string) (*StateResponse, error)
+	RegisterAndPoll(URL, body string, sec int, interval time.Duration) *RegisterAnPollProcess
+	RegisterAndPollContext(ctx context.Context, r *RegisterRequest, interval time.Duration) *RegisterAnPollProcess
+	Watch(ctx context.Context, ID string, interval time.Duration) *RegisterAnPollProcess
+	Cancel(ctx context.Context, ID string) error
+	List(ctx context.Context) ([]StateResponse, error)
+	RegisterSchedule(ctx context.Context, r *RegisterRequest, s Scheduler, from time.Time, n int) ([]Occurrence, error)
+}
+
+var _ API = (*Client)(nil)
 
-// client exposes the functions allowed to talk with the Timehook API
-type client struct {
+// Client exposes the functions allowed to talk with the Timehook API
+type Client struct {
 	key      string
 	httpDoer HTTPDoer
+	baseURL  string
+	retry    RetryPolicy
+}
+
+// Option configures optional parameters of the client
+type Option func(c *Client)
+
+// WithBaseURL sets the base URL of the Timehook API, e.g. a staging or
+// self-hosted deployment. Every endpoint is derived from it.
+func WithBaseURL(URL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(URL, "/")
+	}
 }
 
 // RegisterAndPoll starts a long running process for a single webhook and
 // returns RegisterAnPollProcess which can be query to know the process.
+// It is a convenience built on top of Register and State.
 //
 // The process consists in first registers the webhook to be execute on URL
 // with the body given with a delay in seconds.
 // Second it polls the state until it the webhook finishes or until encounter
 // an irrecoverable error.
-func (c *client) RegisterAndPoll(URL, body string, sec int, interval time.Duration) *RegisterAnPollProcess {
+func (c *Client) RegisterAndPoll(URL, body string, sec int, interval time.Duration) *RegisterAnPollProcess {
+	r := &RegisterRequest{
+		URL:   URL,
+		Body:  strings.NewReader(body),
+		Delay: time.Duration(sec) * time.Second,
+	}
+	return c.RegisterAndPollContext(context.Background(), r, interval)
+}
+
+// RegisterAndPollContext is like RegisterAndPoll for the webhook described
+// by r but the process is bound to ctx. When ctx is done polling stops and
+// the process finishes cancelled.
+// Requests rate limited by the server are retried according to the client
+// retry policy and reported to the process.
+func (c *Client) RegisterAndPollContext(ctx context.Context, r *RegisterRequest, interval time.Duration) *RegisterAnPollProcess {
 	proc := NewRegisterAnPollProcess()
+	ctx = ContextWithRetryNotify(ctx, proc.Retry)
 	go func() {
 		proc.Connect()
-		rr, err := c.register(URL, body, sec)
-		if err != nil {
-			proc.Error(err)
+		rr, err := c.Register(ctx, r)
+		switch {
+		case ctx.Err() != nil:
+			proc.Cancel(ctx.Err())
+			return
+		case err != nil:
+			proc.Fail(err)
 			return
 		}
 
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for range ticker.C {
-			sr, err := c.state(rr.ID)
-			if err != nil {
-				proc.Error(err)
-			} else {
-				proc.State(sr)
-			}
+		proc.setID(rr.ID)
+		c.poll(ctx, proc, rr.statesLink(), interval)
+	}()
 
-			if proc.IsFinished() {
-				break
-			}
-		}
+	return proc
+}
+
+// Watch starts a long running process which polls the state of the webhook
+// already registered identify by ID until it finishes, until encounter an
+// irrecoverable error or until ctx is done.
+func (c *Client) Watch(ctx context.Context, ID string, interval time.Duration) *RegisterAnPollProcess {
+	proc := NewRegisterAnPollProcess()
+	proc.id = ID
+	ctx = ContextWithRetryNotify(ctx, proc.Retry)
+	go func() {
+		proc.Connect()
+		c.poll(ctx, proc, statesPath(ID), interval)
 	}()
 
 	return proc
 }
 
-// isFinal returns if StateResponse or err is a final state
-func isFinal(state *StateResponse, err error) bool {
-	if state != nil {
-		return state.Status == "failed" || state.Status == "succeeded" || state.Status == "timeout"
-	}
+// poll queries the state at link every interval and indicates it to the
+// process until the process finishes or ctx is done. When the server rate
+// limits a query it waits as long as Retry-After asks before the next one.
+func (c *Client) poll(ctx context.Context, proc *RegisterAnPollProcess, link string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			proc.Cancel(ctx.Err())
+			return
+		case <-ticker.C:
+		}
 
-	if err == ErrUnauthorized {
-		return true
-	}
+		sr, err := c.state(ctx, link)
+		switch {
+		case ctx.Err() != nil:
+			proc.Cancel(ctx.Err())
+		case err != nil:
+			proc.Error(err)
+		default:
+			proc.State(sr)
+		}
 
-	return false
+		if proc.IsFinished() {
+			return
+		}
+		if d := rateLimitDelay(err); d > 0 {
+			if err := sleep(ctx, d); err != nil {
+				proc.Cancel(err)
+				return
+			}
+		}
+	}
 }
 
-// isSuccess returns if StateResponse or err is a final state and succeeded
-func isSuccess(state *StateResponse, err error) bool {
-	return isFinal(state, err) && err == nil && state.Status == "succeeded"
-}
+// Register registers the webhook described by r and returns a
+// RegisterResponse or error. The body is streamed to the API.
+func (c *Client) Register(ctx context.Context, r *RegisterRequest) (*RegisterResponse, error) {
+	if _, err := r.seconds(time.Now()); err != nil {
+		return nil, err
+	}
 
-// register registers a new webhook and returns a RegisterResponse or error
-func (c *client) register(URL, body string, delay int) (*RegisterResponse, error) {
-	req, err := http.NewRequest(http.MethodPost, registerURL, strings.NewReader(body))
+	req, err := http.NewRequest(http.MethodPost, c.endpoint("/webhooks"), r.Body)
 	if err != nil {
 		return nil, fmt.Errorf("can not create new request: %s", err)
 	}
-	req.Header.Set("X-Webhook", URL)
-	req.Header.Set("X-Seconds", strconv.Itoa(delay))
+	req = req.WithContext(ctx)
+	if err := setBody(req, r.Body); err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Webhook", r.URL)
+	req.Header.Set("Content-Type", r.contentType())
+	if r.Method != "" {
+		req.Header.Set("X-Method", strings.ToUpper(r.Method))
+	}
+	if err := r.setHeaders(req); err != nil {
+		return nil, err
+	}
 
-	b, err := c.execute(req, 201)
+	// the delay is counted again on every attempt, as retries take time
+	b, err := c.execute(req, 201, func(req *http.Request) error {
+		sec, err := r.seconds(time.Now())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Seconds", strconv.Itoa(sec))
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -127,14 +221,59 @@ func (c *client) register(URL, body string, delay int) (*RegisterResponse, error
 	return &rr, nil
 }
 
-// state query the webhook identify by ID and returns StateResponse or error
-func (c *client) state(ID string) (*StateResponse, error) {
-	req, err := http.NewRequest(http.MethodGet, stateURL+"/"+ID, nil)
+// State query the webhook identify by ID and returns StateResponse or error
+func (c *Client) State(ctx context.Context, ID string) (*StateResponse, error) {
+	return c.state(ctx, statesPath(ID))
+}
+
+// Cancel cancels the webhook identify by ID so it is not sent
+func (c *Client) Cancel(ctx context.Context, ID string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.endpoint("/webhooks/"+url.PathEscape(ID)), nil)
+	if err != nil {
+		return fmt.Errorf("can not create new request: %s", err)
+	}
+	req = req.WithContext(ctx)
+
+	_, err = c.execute(req, 204, nil)
+	return err
+}
+
+// List returns the state of the webhooks registered with the API key
+func (c *Client) List(ctx context.Context) ([]StateResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, c.endpoint("/webhooks"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not create new request: %s", err)
+	}
+	req = req.WithContext(ctx)
+
+	b, err := c.execute(req, 200, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var srs []StateResponse
+	if err := json.Unmarshal(b, &srs); err != nil {
+		return nil, fmt.Errorf("can not parse response %s: %s", b, err)
+	}
+
+	return srs, nil
+}
+
+// state query the webhook state at the link given, either a path relative
+// to the base URL or an absolute URL on its host, and returns StateResponse
+// or error
+func (c *Client) state(ctx context.Context, link string) (*StateResponse, error) {
+	URL, err := c.resolve(link)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, URL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("can not query state: %s", err)
 	}
+	req = req.WithContext(ctx)
 
-	b, err := c.execute(req, 200)
+	b, err := c.execute(req, 200, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -147,37 +286,127 @@ func (c *client) state(ID string) (*StateResponse, error) {
 	return &sr, nil
 }
 
-// execute configures common requests parameters, sends the HTTP request and
-// returns response body or error
-func (c *client) execute(req *http.Request, codeWanted int) ([]byte, error) {
+// execute configures common requests parameters, sends the HTTP request
+// retrying it according to the retry policy while the server rate limits it
+// and returns response body or error. prepare, if any, is called before
+// every attempt to set what depends on when the request is sent.
+func (c *Client) execute(req *http.Request, codeWanted int, prepare func(*http.Request) error) ([]byte, error) {
 	req.Header.Set("Authorization", "Bearer "+c.key)
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for attempt := 1; ; attempt++ {
+		if prepare != nil {
+			if err := prepare(req); err != nil {
+				return nil, err
+			}
+		}
+		b, err := c.do(req, codeWanted)
+		apiErr, ok := err.(*APIError)
+		if !ok || !apiErr.Is(ErrTooManyRequests) || attempt >= c.retry.MaxAttempts {
+			return b, err
+		}
+		if req.Body != nil && req.GetBody == nil { // body can not be sent again
+			return b, err
+		}
+
+		delay, ok := c.retry.delay(attempt, apiErr.Header)
+		if !ok || !waits(req.Context(), delay) { // the server asks to wait longer
+			return b, err
+		}
+
+		r := Retry{
+			Attempt:  attempt,
+			Delay:    delay,
+			Endpoint: apiErr.Endpoint,
+			Err:      err,
+		}
+		notifyRetry(req.Context(), r)
+		if err := sleep(req.Context(), r.Delay); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("can not rewind body: %s", err)
+			}
+		}
+	}
+}
+
+// do sends the HTTP request once and returns response body or error, an
+// *APIError when the status code is not the one wanted
+func (c *Client) do(req *http.Request, codeWanted int) ([]byte, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.httpDoer.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("can not execute request: %s", err)
+		return nil, &NetworkError{Op: "execute request", Endpoint: req.Method + " " + req.URL.String(), Err: err}
 	}
 	defer resp.Body.Close()
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("can not read body: %s", err)
+		return nil, &NetworkError{Op: "read body", Endpoint: req.Method + " " + req.URL.String(), Err: err}
 	}
 
-	switch c := resp.StatusCode; {
-	case c == 401:
-		return nil, ErrUnauthorized
-	case c == 429:
-		return nil, ErrTooManyRequests
-	case c != codeWanted:
-		return nil, fmt.Errorf("wrong response registering webhook: %s, %s\n", resp.Status, b)
+	if resp.StatusCode != codeWanted {
+		return nil, newAPIError(req, resp, b)
 	}
 
 	return b, nil
 }
 
-// New returns a new Timehook client given the API key and httpDoer implementation
-func New(key string, httpDoer HTTPDoer) *client {
-	return &client{key, httpDoer}
+// endpoint returns the URL of the API resource at path, joined to the base
+// URL
+func (c *Client) endpoint(path string) string {
+	return c.baseURL + "/" + strings.TrimLeft(path, "/")
+}
+
+// resolve returns the URL of the API resource at a link returned by the
+// API, either a path joined to the base URL or an absolute URL. Absolute
+// URLs must have the scheme and host of the base URL, as requests carry the
+// API key.
+func (c *Client) resolve(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid link %q: %s", link, err)
+	}
+	if !u.IsAbs() && u.Host == "" {
+		return c.endpoint(link), nil
+	}
+	base, err := url.Parse(c.baseURL)
+	if err != nil || u.Scheme != base.Scheme || u.Host != base.Host {
+		return "", fmt.Errorf("link %s out of the API at %s", link, c.baseURL)
+	}
+	return link, nil
+}
+
+// statesLink returns the HAL link to query the state of the webhook, falling
+// back to the well known path when the server does not return it
+func (rr *RegisterResponse) statesLink() string {
+	if rr.Links.States != "" {
+		return rr.Links.States
+	}
+	return statesPath(rr.ID)
+}
+
+// statesPath returns the path to query the state of the webhook identified
+// by ID
+func statesPath(ID string) string {
+	return "/states/" + url.PathEscape(ID)
+}
+
+// New returns a new Timehook client given the API key and httpDoer
+// implementation, configured with the options given
+func New(key string, httpDoer HTTPDoer, opts ...Option) *Client {
+	c := &Client{key: key, httpDoer: httpDoer, baseURL: DefaultBaseURL, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
@@ -1,6 +1,8 @@
 package timehook_test
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"testing"
 	"time"
@@ -54,3 +56,382 @@ func TestRegisterAndPoll_HTTPRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestRegisterAndPoll_WithBaseURL(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.RegisteredSuccess(),
+		mock.StateSucceeded(),
+	})
+	client := timehook.New("api-key", HTTPClient, timehook.WithBaseURL("http://localhost:8080/timehook/"))
+
+	// when
+	proc := client.RegisterAndPoll("https://the-domain.com", `{"foo" : "bar"}`, 5, 1*time.Nanosecond)
+	for range proc.C {
+	}
+
+	// then
+	want := []string{
+		"http://localhost:8080/timehook/webhooks",
+		"http://localhost:8080/timehook/states/9e9480a4-271b-4708-993a-064509457a23",
+	}
+	for i, r := range HTTPClient.Spies() {
+		if r.URL.String() != want[i] {
+			t.Errorf("wrong URL want %s got %s", want[i], r.URL.String())
+		}
+	}
+}
+
+func TestRegisterAndPollContext_CancelledBeforeRegister(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{})
+	client := timehook.New("api-key", HTTPClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	proc := client.RegisterAndPollContext(ctx, registerRequest(), 1*time.Nanosecond)
+	for range proc.C {
+	}
+
+	// then
+	if !proc.IsCancelled() {
+		t.Errorf("process not cancelled")
+	}
+	if len(HTTPClient.Spies()) != 0 {
+		t.Errorf("wrong number of requests want 0 got %d", len(HTTPClient.Spies()))
+	}
+}
+
+func TestRegisterAndPollContext_CancelledWhilePolling(t *testing.T) {
+	// given
+	stack := []interface{}{mock.RegisteredSuccess()}
+	for i := 0; i < 50; i++ {
+		stack = append(stack, mock.StateAwaiting())
+	}
+	HTTPClient := mock.HTTPClient(stack)
+	client := timehook.New("api-key", HTTPClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// when
+	proc := client.RegisterAndPollContext(ctx, registerRequest(), 1*time.Millisecond)
+	var last timehook.Event
+	for e := range proc.C {
+		if e.Kind == timehook.EventAwaiting {
+			cancel()
+		}
+		last = e
+	}
+
+	// then
+	if !proc.IsCancelled() {
+		t.Errorf("process not cancelled")
+	}
+	if proc.IsSucceeded() {
+		t.Errorf("cancelled process succeeded")
+	}
+	if last.Kind != timehook.EventCancelled || last.Err != context.Canceled {
+		t.Errorf("wrong last event want %s with %s got %s with %v", timehook.EventCancelled, context.Canceled, last.Kind, last.Err)
+	}
+	for _, r := range HTTPClient.Spies() {
+		if r.Context().Err() != context.Canceled {
+			t.Errorf("request %s not bound to the context", r.URL)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess()})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	rr, err := client.Register(context.Background(), registerRequest())
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rr.ID != "9e9480a4-271b-4708-993a-064509457a23" {
+		t.Errorf("wrong ID want %s got %s", "9e9480a4-271b-4708-993a-064509457a23", rr.ID)
+	}
+	if rr.Links.States != "/states/9e9480a4-271b-4708-993a-064509457a23" {
+		t.Errorf("wrong states link want %s got %s", "/states/9e9480a4-271b-4708-993a-064509457a23", rr.Links.States)
+	}
+}
+
+func TestWatch_EventsWithID(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.Unauthorized()})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	proc := client.Watch(context.Background(), "the-id", 1*time.Nanosecond)
+
+	// then
+	var kinds []timehook.EventKind
+	for e := range proc.C {
+		kinds = append(kinds, e.Kind)
+		if e.WebhookID != "the-id" {
+			t.Errorf("wrong webhook ID of %s event want %s got %q", e.Kind, "the-id", e.WebhookID)
+		}
+	}
+	if len(kinds) != 2 || kinds[0] != timehook.EventConnecting || kinds[1] != timehook.EventError {
+		t.Errorf("wrong events want [connecting error] got %v", kinds)
+	}
+}
+
+func TestRegisterAndPoll_RetryEventWithID(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess(), mock.TooManyRequest429(), mock.StateSucceeded()})
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+
+	// when
+	proc := client.RegisterAndPoll("https://the-domain.com", `{"foo" : "bar"}`, 5, 1*time.Nanosecond)
+
+	// then
+	var retried bool
+	for e := range proc.C {
+		if e.Kind != timehook.EventRetry {
+			continue
+		}
+		retried = true
+		if e.WebhookID != "9e9480a4-271b-4708-993a-064509457a23" {
+			t.Errorf("wrong webhook ID of retry event want %s got %q", "9e9480a4-271b-4708-993a-064509457a23", e.WebhookID)
+		}
+	}
+	if !retried {
+		t.Error("retry event expected")
+	}
+}
+
+func TestState_EscapedID(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.StateSucceeded(), mock.StateSucceeded()})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	client.State(context.Background(), "../webhooks?all=1")
+	client.Watch(context.Background(), "a/b#c", 1*time.Nanosecond).Result()
+
+	// then
+	for i, want := range []string{
+		"https://api.timehook.io/states/..%2Fwebhooks%3Fall=1",
+		"https://api.timehook.io/states/a%2Fb%23c",
+	} {
+		if got := HTTPClient.Spies()[i].URL.String(); got != want {
+			t.Errorf("wrong URL want %s got %s", want, got)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.StateSucceeded()})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	sr, err := client.State(context.Background(), "9e9480a4-271b-4708-993a-064509457a23")
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	req := HTTPClient.Spies()[0]
+	if req.Method != "GET" {
+		t.Errorf("wrong method want %s got %s", "GET", req.Method)
+	}
+	if req.URL.String() != "https://api.timehook.io/states/9e9480a4-271b-4708-993a-064509457a23" {
+		t.Errorf("wrong URL want %s got %s", "https://api.timehook.io/states/9e9480a4-271b-4708-993a-064509457a23", req.URL.String())
+	}
+	if sr.Status != "succeeded" {
+		t.Errorf("wrong status want %s got %s", "succeeded", sr.Status)
+	}
+	if sr.SucceededAt != "2018-01-29T12:32:56+0000" {
+		t.Errorf("wrong succeededAt want %s got %s", "2018-01-29T12:32:56+0000", sr.SucceededAt)
+	}
+}
+
+func TestRegisterAndPoll_DoneWithoutDraining(t *testing.T) {
+	// given
+	stack := []interface{}{mock.RegisteredSuccess()}
+	for i := 0; i < 20; i++ {
+		stack = append(stack, mock.StateAwaiting())
+	}
+	stack = append(stack, mock.StateSucceeded())
+	HTTPClient := mock.HTTPClient(stack)
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	proc := client.RegisterAndPoll("https://the-domain.com", `{"foo" : "bar"}`, 5, 1*time.Nanosecond)
+	select {
+	case <-proc.Done():
+	case <-time.After(1 * time.Second):
+		t.Fatalf("process not done")
+	}
+
+	// then
+	res, err := proc.Result()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if res.Outcome != timehook.OutcomeSucceeded {
+		t.Errorf("wrong outcome want %s got %s", timehook.OutcomeSucceeded, res.Outcome)
+	}
+	if res.WebhookID != "9e9480a4-271b-4708-993a-064509457a23" {
+		t.Errorf("wrong webhook ID want %s got %s", "9e9480a4-271b-4708-993a-064509457a23", res.WebhookID)
+	}
+	var events int
+	for range proc.C {
+		events++
+	}
+	if events != 10 { // those buffered in C, the queue is dropped taking the result
+		t.Errorf("wrong number of events want %d got %d", 10, events)
+	}
+}
+
+func TestRegisterAndPoll_ResultError(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.Unauthorized()})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	proc := client.RegisterAndPoll("https://the-domain.com", `{"foo" : "bar"}`, 5, 1*time.Nanosecond)
+	res, err := proc.Result()
+
+	// then
+	if !errors.Is(err, timehook.ErrUnauthorized) {
+		t.Errorf("wrong error want %s got %v", timehook.ErrUnauthorized, err)
+	}
+	if res.Outcome != timehook.OutcomeError {
+		t.Errorf("wrong outcome want %s got %s", timehook.OutcomeError, res.Outcome)
+	}
+	if res.State != nil {
+		t.Errorf("unexpected state %+v", res.State)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.StateSending(),
+		mock.StateFailed(),
+	})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	proc := client.Watch(context.Background(), "9e9480a4-271b-4708-993a-064509457a23", 1*time.Nanosecond)
+	res, err := proc.Result()
+
+	// then
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if res.Outcome != timehook.OutcomeFailed {
+		t.Errorf("wrong outcome want %s got %s", timehook.OutcomeFailed, res.Outcome)
+	}
+	for _, r := range HTTPClient.Spies() {
+		if r.URL.String() != "https://api.timehook.io/states/9e9480a4-271b-4708-993a-064509457a23" {
+			t.Errorf("wrong URL want %s got %s", "https://api.timehook.io/states/9e9480a4-271b-4708-993a-064509457a23", r.URL.String())
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.Cancelled(), mock.Cancelled()})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	err := client.Cancel(context.Background(), "9e9480a4-271b-4708-993a-064509457a23")
+	client.Cancel(context.Background(), "a/b#c")
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, want := range []string{
+		"https://api.timehook.io/webhooks/9e9480a4-271b-4708-993a-064509457a23",
+		"https://api.timehook.io/webhooks/a%2Fb%23c",
+	} {
+		req := HTTPClient.Spies()[i]
+		if req.Method != "DELETE" {
+			t.Errorf("wrong method want %s got %s", "DELETE", req.Method)
+		}
+		if req.URL.String() != want {
+			t.Errorf("wrong URL want %s got %s", want, req.URL.String())
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.List()})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	srs, err := client.List(context.Background())
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	req := HTTPClient.Spies()[0]
+	if req.Method != "GET" || req.URL.String() != "https://api.timehook.io/webhooks" {
+		t.Errorf("wrong request want %s %s got %s %s", "GET", "https://api.timehook.io/webhooks", req.Method, req.URL.String())
+	}
+	if len(srs) != 2 {
+		t.Fatalf("wrong number of webhooks want %d got %d", 2, len(srs))
+	}
+	if srs[0].Status != timehook.StatusSucceeded || srs[1].Status != timehook.StatusAwaitingClock {
+		t.Errorf("wrong statuses want %s, %s got %s, %s", timehook.StatusSucceeded, timehook.StatusAwaitingClock, srs[0].Status, srs[1].Status)
+	}
+}
+
+func TestRegisterAndPoll_StatesLink(t *testing.T) {
+	tt := []struct {
+		name    string
+		link    string
+		wantURL string // empty when the link is refused
+	}{
+		{name: "path", link: "/states/abc", wantURL: "https://api.timehook.io/states/abc"},
+		{name: "same host", link: "https://api.timehook.io/states/abc", wantURL: "https://api.timehook.io/states/abc"},
+		{name: "other host", link: "https://attacker.example.com/states/abc"},
+		{name: "other scheme", link: "http://api.timehook.io/states/abc"},
+		{name: "scheme relative", link: "//attacker.example.com/states/abc"},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			// given
+			HTTPClient := mock.HTTPClient([]interface{}{
+				mock.RegisteredWithStatesLink(v.link),
+				mock.StateSucceeded(),
+			})
+			client := timehook.New("api-key", HTTPClient)
+
+			// when
+			res, err := client.RegisterAndPoll("https://the-domain.com", `{"foo" : "bar"}`, 5, 1*time.Nanosecond).Result()
+
+			// then
+			spies := HTTPClient.Spies()
+			if v.wantURL == "" {
+				if res.Outcome != timehook.OutcomeError || err == nil {
+					t.Errorf("wrong result want %s with error got %s %v", timehook.OutcomeError, res.Outcome, err)
+				}
+				if len(spies) != 1 {
+					t.Errorf("wrong number of requests want %d got %d", 1, len(spies))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(spies) != 2 || spies[1].URL.String() != v.wantURL {
+				t.Errorf("wrong states URL want %s got %v", v.wantURL, spies)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package timehook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrTooManyRequests = errors.New("server responses 429 too many request")
+	ErrUnauthorized    = errors.New("server responses 401 unauthorized request")
+)
+
+// isTooManyRequests returns if err is a request rate limited by the server
+func isTooManyRequests(err error) bool {
+	return errors.Is(err, ErrTooManyRequests)
+}
+
+// APIError is returned when the Timehook API responses with an unexpected
+// status code. It matches ErrUnauthorized and ErrTooManyRequests with
+// errors.Is for 401 and 429 responses.
+type APIError struct {
+	Endpoint   string // method and URL of the request
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+	// Message is the error message parsed from the body when the server
+	// returns an error document
+	Message string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = strings.TrimSpace(string(e.Body))
+	}
+	if msg == "" {
+		return fmt.Sprintf("server responses %s on %s", e.Status, e.Endpoint)
+	}
+	return fmt.Sprintf("server responses %s on %s: %s", e.Status, e.Endpoint, msg)
+}
+
+// Is reports whether target is the sentinel error of the status code
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// NetworkError is returned when a request can not be sent to the Timehook API
+// or its response can not be read
+type NetworkError struct {
+	Op       string // operation failed
+	Endpoint string // method and URL of the request
+	Err      error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("can not %s: %s", e.Op, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// newAPIError returns an APIError for the request and response given with
+// the response body already read
+func newAPIError(req *http.Request, resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		Endpoint:   req.Method + " " + req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+
+	var doc struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+		Detail  string `json:"detail"`
+	}
+	if json.Unmarshal(body, &doc) == nil {
+		for _, m := range []string{doc.Message, doc.Error, doc.Detail} {
+			if m != "" {
+				e.Message = m
+				break
+			}
+		}
+	}
+
+	return e
+}
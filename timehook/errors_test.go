@@ -0,0 +1,93 @@
+package timehook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/timehook/cli-client/mock"
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestAPIError(t *testing.T) {
+	tt := []struct {
+		name        string
+		given       *http.Response
+		wantCode    int
+		wantMessage string
+		wantError   string
+		wantIs      error
+	}{
+		{
+			name:        "not found with error document",
+			given:       mock.NotFound(),
+			wantCode:    404,
+			wantMessage: "webhook not found",
+			wantError:   "server responses 404 Not Found on GET https://api.timehook.io/states/the-id: webhook not found",
+		},
+		{
+			name:      "internal server error with plain body",
+			given:     mock.InternalServerError(),
+			wantCode:  500,
+			wantError: "server responses 500 Internal Server Error on GET https://api.timehook.io/states/the-id: upstream connect error",
+		},
+		{
+			name:      "unauthorized",
+			given:     mock.Unauthorized(),
+			wantCode:  401,
+			wantError: "server responses 401 Unauthorized on GET https://api.timehook.io/states/the-id",
+			wantIs:    timehook.ErrUnauthorized,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			HTTPClient := mock.HTTPClient([]interface{}{v.given})
+			client := timehook.New("api-key", HTTPClient)
+
+			_, err := client.State(context.Background(), "the-id")
+
+			var apiErr *timehook.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("wrong error type want *timehook.APIError got %T", err)
+			}
+			if apiErr.StatusCode != v.wantCode {
+				t.Errorf("wrong status code want %d got %d", v.wantCode, apiErr.StatusCode)
+			}
+			if apiErr.Message != v.wantMessage {
+				t.Errorf("wrong message want %q got %q", v.wantMessage, apiErr.Message)
+			}
+			if apiErr.Error() != v.wantError {
+				t.Errorf("wrong error want %q got %q", v.wantError, apiErr.Error())
+			}
+			if v.wantIs != nil && !errors.Is(err, v.wantIs) {
+				t.Errorf("error %s is not %s", err, v.wantIs)
+			}
+			if errors.Is(err, timehook.ErrTooManyRequests) {
+				t.Errorf("error %s is %s", err, timehook.ErrTooManyRequests)
+			}
+		})
+	}
+}
+
+func TestNetworkError(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{errors.New("connection reset by peer")})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	_, err := client.State(context.Background(), "the-id")
+
+	// then
+	var netErr *timehook.NetworkError
+	if !errors.As(err, &netErr) {
+		t.Fatalf("wrong error type want *timehook.NetworkError got %T", err)
+	}
+	if netErr.Endpoint != "GET https://api.timehook.io/states/the-id" {
+		t.Errorf("wrong endpoint want %s got %s", "GET https://api.timehook.io/states/the-id", netErr.Endpoint)
+	}
+	if err.Error() != "can not execute request: connection reset by peer" {
+		t.Errorf("wrong error want %q got %q", "can not execute request: connection reset by peer", err.Error())
+	}
+}
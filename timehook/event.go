@@ -0,0 +1,52 @@
+package timehook
+
+import "time"
+
+// EventKind identifies the step of a RegisterAnPollProcess an Event reports
+type EventKind string
+
+const (
+	EventConnecting EventKind = "connecting"
+	EventScheduled  EventKind = "scheduled"
+	EventAwaiting   EventKind = "awaiting"
+	EventSending    EventKind = "sending"
+	EventSucceeded  EventKind = "succeeded"
+	EventFailed     EventKind = "failed"
+	EventTimeout    EventKind = "timeout"
+	EventUnknown    EventKind = "unknown"
+	EventRetry      EventKind = "retry"
+	EventError      EventKind = "error"
+	EventCancelled  EventKind = "cancelled"
+)
+
+// Event reports a step of a RegisterAnPollProcess
+type Event struct {
+	Kind      EventKind
+	WebhookID string
+	// Time is when the step happened, as reported by the server for the
+	// steps caused by a state, zero when the server date is not valid
+	Time time.Time
+	// Elapsed is the time since the webhook was registered for the steps
+	// caused by a state
+	Elapsed time.Duration
+	// State is the state which caused the step, if any
+	State *StateResponse
+	// Retry is the request retried on EventRetry
+	Retry *Retry
+	// Err is the error found on EventError, EventRetry and EventCancelled
+	Err error
+
+	final bool // the error finishes the process although rate limited
+}
+
+// IsFinal returns if the event finishes the process, as the errors found
+// do unless the server rate limited a request retried later
+func (e Event) IsFinal() bool {
+	switch e.Kind {
+	case EventSucceeded, EventFailed, EventTimeout, EventUnknown, EventCancelled:
+		return true
+	case EventError:
+		return e.final || !isTooManyRequests(e.Err)
+	}
+	return false
+}
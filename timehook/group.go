@@ -0,0 +1,191 @@
+package timehook
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Group follows many registered webhooks together until every one finishes.
+// A single goroutine polls their states in turn, one request at a time, so
+// when the server rate limits a request polling backs off for the whole
+// group. The events of every webhook are merged on C, in order for each
+// webhook, and C is closed once every event has been delivered after the
+// group finishes. Callers only interested in the result may call Result
+// instead of draining C, which drops the events not delivered yet.
+type Group struct {
+	C       chan Event
+	done    chan struct{}
+	discard chan struct{} // closed to drop the events once nobody reads C
+	once    sync.Once
+
+	ids   []string
+	procs map[string]*RegisterAnPollProcess
+}
+
+// GroupResult is the final result of a Group
+type GroupResult struct {
+	// Results are the results of the webhooks in the order they were given
+	Results []Result
+	// Errors are the errors which finished the webhooks by ID
+	Errors map[string]error
+	// Counts are the number of webhooks finished with every outcome
+	Counts map[Outcome]int
+}
+
+// Result is the result of the webhook identified by ID, if it is in the
+// group
+func (r GroupResult) Result(ID string) (Result, bool) {
+	for _, res := range r.Results {
+		if res.WebhookID == ID {
+			return res, true
+		}
+	}
+	return Result{}, false
+}
+
+// WatchGroup starts a long running process which polls every interval the
+// state of the webhooks already registered identified by IDs until all of
+// them finish or until ctx is done.
+func (c *Client) WatchGroup(ctx context.Context, IDs []string, interval time.Duration) *Group {
+	g := &Group{
+		C:       make(chan Event, 10),
+		done:    make(chan struct{}),
+		discard: make(chan struct{}),
+		procs:   make(map[string]*RegisterAnPollProcess),
+	}
+	for _, ID := range IDs {
+		if _, ok := g.procs[ID]; ok {
+			continue
+		}
+		proc := NewRegisterAnPollProcess()
+		proc.id = ID
+		g.ids = append(g.ids, ID)
+		g.procs[ID] = proc
+	}
+
+	var wg sync.WaitGroup
+	for _, ID := range g.ids {
+		wg.Add(1)
+		go func(ID string, proc *RegisterAnPollProcess) {
+			defer wg.Done()
+			for e := range proc.C {
+				e.WebhookID = ID
+				select {
+				case g.C <- e:
+				case <-g.discard:
+				}
+			}
+		}(ID, g.procs[ID])
+	}
+	go func() {
+		wg.Wait()
+		close(g.C)
+	}()
+
+	go func() {
+		for _, ID := range g.ids {
+			g.procs[ID].Connect()
+		}
+		c.pollGroup(ctx, g, interval)
+		for _, ID := range g.ids {
+			<-g.procs[ID].Done()
+		}
+		close(g.done)
+	}()
+
+	return g
+}
+
+// pollGroup queries the state of every webhook of the group not finished
+// yet every interval and indicates it to its process until all of them
+// finish or ctx is done. When the server rate limits a query the round
+// stops and it waits as long as Retry-After asks before the next one.
+func (c *Client) pollGroup(ctx context.Context, g *Group, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for pending := len(g.ids); pending > 0; {
+		select {
+		case <-ctx.Done():
+			g.cancel(ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		pending = 0
+		var wait time.Duration
+		for _, ID := range g.ids {
+			proc := g.procs[ID]
+			if proc.IsFinished() {
+				continue
+			}
+			if wait > 0 { // rate limited, the rest wait for the next round
+				pending++
+				continue
+			}
+
+			sr, err := c.state(ContextWithRetryNotify(ctx, proc.Retry), statesPath(ID))
+			switch {
+			case ctx.Err() != nil:
+				g.cancel(ctx.Err())
+				return
+			case err != nil:
+				proc.Error(err)
+				wait = rateLimitDelay(err)
+			default:
+				proc.State(sr)
+			}
+
+			if !proc.IsFinished() {
+				pending++
+			}
+		}
+		if wait > 0 {
+			if err := sleep(ctx, wait); err != nil {
+				g.cancel(err)
+				return
+			}
+		}
+	}
+}
+
+// cancel finishes cancelled every webhook of the group not finished yet
+func (g *Group) cancel(err error) {
+	for _, ID := range g.ids {
+		g.procs[ID].Cancel(err)
+	}
+}
+
+// Done returns a channel closed when every webhook of the group finishes
+func (g *Group) Done() <-chan struct{} { return g.done }
+
+// Result waits for the group to finish and returns the result of every
+// webhook. The events not delivered on C yet are dropped, so C is closed
+// even when nobody reads it.
+func (g *Group) Result() GroupResult {
+	<-g.done
+	g.once.Do(func() { close(g.discard) })
+	res := GroupResult{Errors: make(map[string]error), Counts: make(map[Outcome]int)}
+	for _, ID := range g.ids {
+		r, err := g.procs[ID].Result()
+		r.WebhookID = ID
+		if err != nil {
+			res.Errors[ID] = err
+		}
+		res.Results = append(res.Results, r)
+		res.Counts[r.Outcome]++
+	}
+	return res
+}
+
+// Counts returns the number of webhooks of the group with every status known
+// so far, those not queried yet are not counted
+func (g *Group) Counts() map[Status]int {
+	counts := make(map[Status]int)
+	for _, ID := range g.ids {
+		if s := g.procs[ID].Status(); s != "" {
+			counts[s]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,183 @@
+package timehook_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/mock"
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestWatchGroup(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.StateSending(),
+		mock.StateAwaiting(),
+		mock.StateSucceeded(),
+		mock.StateFailed(),
+	})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	g := client.WatchGroup(context.Background(), []string{"first", "second", "first"}, 1*time.Nanosecond)
+	events := map[string][]timehook.EventKind{}
+	for e := range g.C {
+		events[e.WebhookID] = append(events[e.WebhookID], e.Kind)
+	}
+	res := g.Result()
+
+	// then
+	if len(res.Results) != 2 {
+		t.Fatalf("wrong number of results want %d got %d", 2, len(res.Results))
+	}
+	for i, want := range []timehook.Result{
+		{WebhookID: "first", Outcome: timehook.OutcomeSucceeded},
+		{WebhookID: "second", Outcome: timehook.OutcomeFailed},
+	} {
+		got := res.Results[i]
+		if got.WebhookID != want.WebhookID || got.Outcome != want.Outcome {
+			t.Errorf("wrong result %d want %s %s got %s %s", i, want.WebhookID, want.Outcome, got.WebhookID, got.Outcome)
+		}
+	}
+	if res.Counts[timehook.OutcomeSucceeded] != 1 || res.Counts[timehook.OutcomeFailed] != 1 {
+		t.Errorf("wrong counts %v", res.Counts)
+	}
+	if len(res.Errors) != 0 {
+		t.Errorf("unexpected errors %v", res.Errors)
+	}
+
+	wantEvents := map[string][]timehook.EventKind{
+		"first":  {timehook.EventConnecting, timehook.EventScheduled, timehook.EventSending, timehook.EventSucceeded},
+		"second": {timehook.EventConnecting, timehook.EventScheduled, timehook.EventAwaiting, timehook.EventSending, timehook.EventFailed},
+	}
+	for ID, want := range wantEvents {
+		if len(events[ID]) != len(want) {
+			t.Errorf("wrong events of %s want %v got %v", ID, want, events[ID])
+			continue
+		}
+		for i := range want {
+			if events[ID][i] != want[i] {
+				t.Errorf("wrong events of %s want %v got %v", ID, want, events[ID])
+				break
+			}
+		}
+	}
+
+	wantURLs := []string{"/states/first", "/states/second", "/states/first", "/states/second"}
+	for i, r := range HTTPClient.Spies() {
+		if r.URL.Path != wantURLs[i] {
+			t.Errorf("wrong URL of request %d want %s got %s", i, wantURLs[i], r.URL.Path)
+		}
+	}
+}
+
+func TestWatchGroup_ResultWithoutReading(t *testing.T) {
+	// given
+	goroutines := runtime.NumGoroutine()
+	var states []interface{}
+	for i := 0; i < 30; i++ {
+		states = append(states, mock.StateAwaiting())
+	}
+	HTTPClient := mock.HTTPClient(append(states, mock.StateSucceeded(), mock.StateSucceeded()))
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	res := client.WatchGroup(context.Background(), []string{"first", "second"}, 1*time.Nanosecond).Result()
+
+	// then
+	if res.Counts[timehook.OutcomeSucceeded] != 2 {
+		t.Errorf("wrong counts %v", res.Counts)
+	}
+	waitGoroutines(t, goroutines)
+}
+
+func TestWatchGroup_RateLimited(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.StateSucceeded(),
+		mock.TooManyRequest429(),
+		mock.StateSucceeded(),
+	})
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+
+	// when
+	g := client.WatchGroup(context.Background(), []string{"first", "second"}, 1*time.Nanosecond)
+	var retried []string
+	for e := range g.C {
+		if e.Kind == timehook.EventRetry {
+			retried = append(retried, e.WebhookID)
+		}
+	}
+	res := g.Result()
+
+	// then
+	if res.Counts[timehook.OutcomeSucceeded] != 2 {
+		t.Errorf("wrong counts %v", res.Counts)
+	}
+	if len(retried) != 1 || retried[0] != "second" {
+		t.Errorf("wrong webhooks retried want %v got %v", []string{"second"}, retried)
+	}
+}
+
+func TestWatchGroup_Error(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.NotFound(),
+		mock.StateSucceeded(),
+	})
+	client := timehook.New("api-key", HTTPClient)
+
+	// when
+	res := client.WatchGroup(context.Background(), []string{"first", "second"}, 1*time.Nanosecond).Result()
+
+	// then
+	if r, _ := res.Result("first"); r.Outcome != timehook.OutcomeError {
+		t.Errorf("wrong outcome of first want %s got %s", timehook.OutcomeError, r.Outcome)
+	}
+	if res.Errors["first"] == nil {
+		t.Error("error of first expected")
+	}
+	if r, _ := res.Result("second"); r.Outcome != timehook.OutcomeSucceeded {
+		t.Errorf("wrong outcome of second want %s got %s", timehook.OutcomeSucceeded, r.Outcome)
+	}
+}
+
+func TestWatchGroup_Cancelled(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{})
+	client := timehook.New("api-key", HTTPClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	res := client.WatchGroup(ctx, []string{"first", "second"}, 1*time.Hour).Result()
+
+	// then
+	if res.Counts[timehook.OutcomeCancelled] != 2 {
+		t.Errorf("wrong counts %v", res.Counts)
+	}
+	if len(HTTPClient.Spies()) != 0 {
+		t.Errorf("wrong number of requests want %d got %d", 0, len(HTTPClient.Spies()))
+	}
+}
+
+func TestWatchGroup_Empty(t *testing.T) {
+	// given
+	client := timehook.New("api-key", mock.HTTPClient([]interface{}{}))
+
+	// when
+	g := client.WatchGroup(context.Background(), nil, 1*time.Nanosecond)
+
+	// then
+	select {
+	case <-g.Done():
+	case <-time.After(time.Second):
+		t.Fatal("group not done")
+	}
+	if _, ok := <-g.C; ok {
+		t.Error("unexpected event")
+	}
+}
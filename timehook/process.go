@@ -1,51 +1,117 @@
 package timehook
 
 import (
-	"fmt"
+	"sync"
 	"time"
 )
 
+// RegisterAnPollProcess follows a webhook from its registration until it
+// finishes and emits an Event on C for every step. C is closed once every
+// event has been delivered after the process finishes. Events are queued
+// while nobody receives them, keeping only the last one of the steps which
+// repeat on every poll, as awaiting. Callers only interested in the result
+// may call Result instead of draining C, which drops the events not
+// delivered yet.
+//
+// It is safe to use a process from several goroutines.
 type RegisterAnPollProcess struct {
-	C         chan string
-	succeeded bool
-	finished  bool
-	status    string
+	C       chan Event
+	done    chan struct{}
+	discard chan struct{} // closed to drop the queue once nobody reads C
+	once    sync.Once
+
+	mu       sync.Mutex
+	queue    []Event // events waiting to be delivered on C
+	flushing bool    // a goroutine is delivering the queue
+	finished bool
+	status   string
+	id       string
+	state    *StateResponse
+	outcome  Outcome
+	err      error
+}
+
+// Outcome is how a RegisterAnPollProcess finished
+type Outcome string
+
+const (
+	OutcomeSucceeded Outcome = "succeeded"
+	OutcomeFailed    Outcome = "failed"
+	OutcomeTimeout   Outcome = "timeout"
+	OutcomeUnknown   Outcome = "unknown" // the webhook got an unexpected status
+	OutcomeError     Outcome = "error"
+	OutcomeCancelled Outcome = "cancelled"
+)
+
+// Result is the final result of a RegisterAnPollProcess
+type Result struct {
+	Outcome   Outcome
+	WebhookID string
+	// State is the last state of the webhook known, nil when it was never
+	// queried
+	State *StateResponse
 }
 
 // Connect indicates to the process that is connecting
 func (p *RegisterAnPollProcess) Connect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.finished {
+		p.connect()
+	}
+}
+
+func (p *RegisterAnPollProcess) connect() {
 	if p.status == "not started" {
 		p.status = "connecting"
-		p.C <- "\nconnecting to timehook.io"
+		p.emit(Event{Kind: EventConnecting, Time: time.Now()})
 	}
 }
 
+// setID sets the ID of the webhook followed, once registered
+func (p *RegisterAnPollProcess) setID(ID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.id = ID
+}
+
 // State indicates to the process in which state is
 func (p *RegisterAnPollProcess) State(s *StateResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+
+	if s.ID != "" {
+		p.id = s.ID
+	}
+	p.state = s
+
 	switch s.Status {
-	case "registered":
-		p.Connect()
+	case StatusRegistered:
+		p.connect()
 		p.registered(s)
-	case "awaitingClock":
-		p.Connect()
+	case StatusAwaitingClock:
+		p.connect()
 		p.registered(s)
 		p.awaiting(s)
-	case "sendingHttp":
-		p.Connect()
+	case StatusSendingHTTP:
+		p.connect()
 		p.registered(s)
 		p.sendingHTTP(s)
-	case "succeeded":
-		p.Connect()
+	case StatusSucceeded:
+		p.connect()
 		p.registered(s)
 		p.sendingHTTP(s)
 		p.success(s)
-	case "failed":
-		p.Connect()
+	case StatusFailed:
+		p.connect()
 		p.registered(s)
 		p.sendingHTTP(s)
 		p.failed(s)
-	case "timeout":
-		p.Connect()
+	case StatusTimeout:
+		p.connect()
 		p.registered(s)
 		p.sendingHTTP(s)
 		p.timeout(s)
@@ -57,86 +123,236 @@ func (p *RegisterAnPollProcess) State(s *StateResponse) {
 
 func (p *RegisterAnPollProcess) registered(s *StateResponse) {
 	if p.status == "connecting" {
-		p.C <- fmt.Sprintf("\n[0s] webhook scheduled at %s", s.ScheduledAt)
+		p.emitState(EventScheduled, s, s.ScheduledAt)
 		p.status = "scheduled"
 	}
 }
 
 func (p *RegisterAnPollProcess) awaiting(s *StateResponse) {
-	p.C <- "."
+	p.emitState(EventAwaiting, s, s.AwaitingClockAt)
 }
 
 func (p *RegisterAnPollProcess) sendingHTTP(s *StateResponse) {
 	if p.status == "scheduled" {
-		sec := sinceSec(s.RegisteredAt, s.SendingHttpAt)
-		p.C <- fmt.Sprintf("\n[%0.fs] sending webhook at %s", sec, s.SendingHttpAt)
+		p.emitState(EventSending, s, s.SendingHttpAt)
 		p.status = "sending"
 	}
 }
 
 func (p *RegisterAnPollProcess) success(s *StateResponse) {
-	sec := sinceSec(s.RegisteredAt, s.SucceededAt)
-	p.C <- fmt.Sprintf("\n[%0.fs] webhook succeeded at %s\n\n", sec, s.SucceededAt)
-	p.succeeded = true
-	p.finish()
+	p.emitState(EventSucceeded, s, s.SucceededAt)
+	p.finish(OutcomeSucceeded, nil)
 }
 
 func (p *RegisterAnPollProcess) failed(s *StateResponse) {
-	sec := sinceSec(s.RegisteredAt, s.FailedAt)
-	p.C <- fmt.Sprintf("\n[%0.fs] webhook failed at %s\n\n", sec, s.FailedAt)
-	p.finish()
+	p.emitState(EventFailed, s, s.FailedAt)
+	p.finish(OutcomeFailed, nil)
 }
 
 func (p *RegisterAnPollProcess) timeout(s *StateResponse) {
-	sec := sinceSec(s.RegisteredAt, s.FailedAt)
-	p.C <- fmt.Sprintf("\n[%0.fs] webhook timeout at %s\n\n", sec, s.FailedAt)
-	p.finish()
+	p.emitState(EventTimeout, s, s.FailedAt)
+	p.finish(OutcomeTimeout, nil)
 }
 
 func (p *RegisterAnPollProcess) unknown(s *StateResponse) {
-	p.C <- fmt.Sprintf("\n[??s] exit with unexpected status '%s'\n\n", s.Status)
-	p.finish()
+	p.emit(Event{Kind: EventUnknown, Time: time.Now(), State: s})
+	p.finish(OutcomeUnknown, nil)
+}
+
+// Retry indicates to the process that a request is going to be retried
+func (p *RegisterAnPollProcess) Retry(r Retry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.finished {
+		p.emit(Event{Kind: EventRetry, Time: time.Now(), Retry: &r, Err: r.Err})
+	}
 }
 
-// Error indicates to the process the error found
+// Error indicates to the process the error found. Requests rate limited by
+// the server do not finish the process.
 func (p *RegisterAnPollProcess) Error(err error) {
-	switch err {
-	case ErrTooManyRequests:
-		p.C <- "."
-	default:
-		p.C <- fmt.Sprintf("[Error] %s", err)
-		p.finish()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+
+	p.emit(Event{Kind: EventError, Time: time.Now(), Err: err})
+	if !isTooManyRequests(err) {
+		p.finish(OutcomeError, err)
 	}
 }
 
-func (p *RegisterAnPollProcess) finish() {
-	p.finished = true
-	close(p.C)
+// Fail indicates to the process the error which finishes it, even when the
+// server rate limited the request, e.g. once the registration is given up
+func (p *RegisterAnPollProcess) Fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+
+	p.emit(Event{Kind: EventError, Time: time.Now(), Err: err, final: true})
+	p.finish(OutcomeError, err)
 }
 
-func (p *RegisterAnPollProcess) IsSucceeded() bool { return p.succeeded }
-func (p *RegisterAnPollProcess) IsFinished() bool  { return p.finished }
+// Cancel indicates to the process that it has been cancelled with err
+func (p *RegisterAnPollProcess) Cancel(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
 
-// sinceSec returns the number of seconds between to and from string dates in
-// ISO 8601 format
-func sinceSec(from, to string) float64 {
-	layout := "2006-01-02T15:04:05+0000"
+	p.emit(Event{Kind: EventCancelled, Time: time.Now(), Err: err})
+	p.finish(OutcomeCancelled, err)
+}
+
+// emitState emits an event of the kind given caused by the state s at the
+// timestamp at
+func (p *RegisterAnPollProcess) emitState(kind EventKind, s *StateResponse, at string) {
+	t := parseTimeOrZero(at)
+	p.emit(Event{
+		Kind:    kind,
+		Time:    t,
+		Elapsed: between(s.RegisteredTime(), t),
+		State:   s,
+	})
+}
 
-	fromT, err := time.Parse(layout, from)
-	if err != nil {
-		return 0
+// emit delivers the event on C, or queues it when C is full. It must be
+// called holding the lock.
+func (p *RegisterAnPollProcess) emit(e Event) {
+	e.WebhookID = p.id
+	if !p.flushing {
+		select {
+		case p.C <- e:
+			return
+		default:
+		}
 	}
-	toT, err := time.Parse(layout, to)
-	if err != nil {
-		return 0
+
+	if repeats(e) {
+		p.unqueue(e.Kind)
+	}
+	p.queue = append(p.queue, e)
+	if !p.flushing {
+		p.flushing = true
+		go p.flush()
+	}
+}
+
+// repeats returns if the event is of a step which may repeat on every poll
+func repeats(e Event) bool {
+	return e.Kind == EventAwaiting || e.Kind == EventRetry || e.Kind == EventError && !e.IsFinal()
+}
+
+// unqueue removes the events of the kind given from the queue. It must be
+// called holding the lock.
+func (p *RegisterAnPollProcess) unqueue(kind EventKind) {
+	queue := p.queue[:0]
+	for _, e := range p.queue {
+		if e.Kind != kind {
+			queue = append(queue, e)
+		}
 	}
+	p.queue = queue
+}
+
+// flush delivers the queued events on C until the queue is empty, closing
+// C when the process has finished meanwhile. The queue is dropped once the
+// process is finished and its result has been taken.
+func (p *RegisterAnPollProcess) flush() {
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.flushing = false
+			if p.finished {
+				close(p.C)
+			}
+			p.mu.Unlock()
+			return
+		}
+		e := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		select {
+		case <-p.discard:
+			p.drop()
+			continue
+		default:
+		}
+		select {
+		case p.C <- e:
+		case <-p.discard:
+			p.drop()
+		}
+	}
+}
+
+// drop empties the queue
+func (p *RegisterAnPollProcess) drop() {
+	p.mu.Lock()
+	p.queue = nil
+	p.mu.Unlock()
+}
+
+// finish records the result of the process. It must be called holding the
+// lock.
+func (p *RegisterAnPollProcess) finish(outcome Outcome, err error) {
+	p.finished = true
+	p.outcome = outcome
+	p.err = err
+	close(p.done)
+	if !p.flushing {
+		close(p.C)
+	}
+}
+
+// Done returns a channel closed when the process finishes
+func (p *RegisterAnPollProcess) Done() <-chan struct{} { return p.done }
+
+// Result waits for the process to finish and returns its result and the
+// error which finished it, if any. The events not delivered on C yet are
+// dropped, so C is closed even when nobody reads it.
+func (p *RegisterAnPollProcess) Result() (Result, error) {
+	<-p.done
+	p.once.Do(func() { close(p.discard) })
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return Result{Outcome: p.outcome, WebhookID: p.id, State: p.state}, p.err
+}
+
+// Status returns the last status of the webhook known by the process
+func (p *RegisterAnPollProcess) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state == nil {
+		return ""
+	}
+	return p.state.Status
+}
+
+func (p *RegisterAnPollProcess) IsSucceeded() bool { return p.Status().IsSuccess() }
+
+func (p *RegisterAnPollProcess) IsCancelled() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.outcome == OutcomeCancelled
+}
 
-	return toT.Sub(fromT).Seconds()
+func (p *RegisterAnPollProcess) IsFinished() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.finished
 }
 
 func NewRegisterAnPollProcess() *RegisterAnPollProcess {
 	return &RegisterAnPollProcess{
-		C:      make(chan string, 10),
-		status: "not started",
+		C:       make(chan Event, 10),
+		done:    make(chan struct{}),
+		discard: make(chan struct{}),
+		status:  "not started",
 	}
 }
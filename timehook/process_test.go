@@ -1,20 +1,39 @@
 package timehook_test
 
 import (
+	"context"
 	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/timehook/cli-client/timehook"
 )
 
+// event is the part of an Event checked by the tests of the process
+type event struct {
+	Kind    timehook.EventKind
+	Status  timehook.Status
+	Elapsed time.Duration
+	Err     error
+}
+
+func newEvent(e timehook.Event) event {
+	ev := event{Kind: e.Kind, Elapsed: e.Elapsed, Err: e.Err}
+	if e.State != nil {
+		ev.Status = e.State.Status
+	}
+	return ev
+}
+
 func TestRegisterAnPollProcess(t *testing.T) {
 	tt := []struct {
 		name          string
 		given         func(p *timehook.RegisterAnPollProcess)
 		when          func(p *timehook.RegisterAnPollProcess)
-		wantMsgs      []string
+		wantEvents    []event
 		wantSucceeded bool
+		wantCancelled bool
 		wantFinished  bool
 	}{
 		{
@@ -23,7 +42,7 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.Connect() },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs:      []string{"\nconnecting to timehook.io"},
+			wantEvents:    []event{{Kind: timehook.EventConnecting}},
 		},
 		{
 			name:          "registered with no connecting",
@@ -31,9 +50,9 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateRegistered()) },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs: []string{
-				"\nconnecting to timehook.io",
-				"\n[0s] webhook scheduled at 2018-01-29T12:32:55+0000",
+			wantEvents: []event{
+				{Kind: timehook.EventConnecting},
+				{Kind: timehook.EventScheduled, Status: timehook.StatusRegistered, Elapsed: 30 * time.Second},
 			},
 		},
 		{
@@ -42,7 +61,9 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateRegistered()) },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs:      []string{"\n[0s] webhook scheduled at 2018-01-29T12:32:55+0000"},
+			wantEvents: []event{
+				{Kind: timehook.EventScheduled, Status: timehook.StatusRegistered, Elapsed: 30 * time.Second},
+			},
 		},
 		{
 			name:          "awaiting",
@@ -50,7 +71,9 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateAwaiting()) },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs:      []string{"."},
+			wantEvents: []event{
+				{Kind: timehook.EventAwaiting, Status: timehook.StatusAwaitingClock, Elapsed: 1 * time.Second},
+			},
 		},
 		{
 			name:          "awaiting with no connecting",
@@ -58,10 +81,10 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateAwaiting()) },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs: []string{
-				"\nconnecting to timehook.io",
-				"\n[0s] webhook scheduled at 2018-01-29T12:32:55+0000",
-				".",
+			wantEvents: []event{
+				{Kind: timehook.EventConnecting},
+				{Kind: timehook.EventScheduled, Status: timehook.StatusAwaitingClock, Elapsed: 30 * time.Second},
+				{Kind: timehook.EventAwaiting, Status: timehook.StatusAwaitingClock, Elapsed: 1 * time.Second},
 			},
 		},
 		{
@@ -70,9 +93,9 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateSending()) },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs: []string{
-				"\n[0s] webhook scheduled at 2018-01-29T12:32:55+0000",
-				"\n[30s] sending webhook at 2018-01-29T12:32:55+0000",
+			wantEvents: []event{
+				{Kind: timehook.EventScheduled, Status: timehook.StatusSendingHTTP, Elapsed: 30 * time.Second},
+				{Kind: timehook.EventSending, Status: timehook.StatusSendingHTTP, Elapsed: 30 * time.Second},
 			},
 		},
 		{
@@ -81,10 +104,10 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateSending()) },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs: []string{
-				"\nconnecting to timehook.io",
-				"\n[0s] webhook scheduled at 2018-01-29T12:32:55+0000",
-				"\n[30s] sending webhook at 2018-01-29T12:32:55+0000",
+			wantEvents: []event{
+				{Kind: timehook.EventConnecting},
+				{Kind: timehook.EventScheduled, Status: timehook.StatusSendingHTTP, Elapsed: 30 * time.Second},
+				{Kind: timehook.EventSending, Status: timehook.StatusSendingHTTP, Elapsed: 30 * time.Second},
 			},
 		},
 		{
@@ -93,8 +116,8 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateSucceeded()) },
 			wantSucceeded: true,
 			wantFinished:  true,
-			wantMsgs: []string{
-				"\n[31s] webhook succeeded at 2018-01-29T12:32:56+0000\n\n",
+			wantEvents: []event{
+				{Kind: timehook.EventSucceeded, Status: timehook.StatusSucceeded, Elapsed: 31 * time.Second},
 			},
 		},
 		{
@@ -103,8 +126,8 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateFailed()) },
 			wantSucceeded: false,
 			wantFinished:  true,
-			wantMsgs: []string{
-				"\n[32s] webhook failed at 2018-01-29T12:32:57+0000\n\n",
+			wantEvents: []event{
+				{Kind: timehook.EventFailed, Status: timehook.StatusFailed, Elapsed: 32 * time.Second},
 			},
 		},
 		{
@@ -113,8 +136,8 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateTimeout()) },
 			wantSucceeded: false,
 			wantFinished:  true,
-			wantMsgs: []string{
-				"\n[34s] webhook timeout at 2018-01-29T12:32:59+0000\n\n",
+			wantEvents: []event{
+				{Kind: timehook.EventTimeout, Status: timehook.StatusTimeout, Elapsed: 34 * time.Second},
 			},
 		},
 		{
@@ -123,8 +146,8 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateUnknown()) },
 			wantSucceeded: false,
 			wantFinished:  true,
-			wantMsgs: []string{
-				"\n[??s] exit with unexpected status 'unknown-status'\n\n",
+			wantEvents: []event{
+				{Kind: timehook.EventUnknown, Status: "unknown-status"},
 			},
 		},
 		{
@@ -133,8 +156,8 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.Error(timehook.ErrTooManyRequests) },
 			wantSucceeded: false,
 			wantFinished:  false,
-			wantMsgs: []string{
-				".",
+			wantEvents: []event{
+				{Kind: timehook.EventError, Err: timehook.ErrTooManyRequests},
 			},
 		},
 		{
@@ -143,8 +166,29 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.Error(timehook.ErrUnauthorized) },
 			wantSucceeded: false,
 			wantFinished:  true,
-			wantMsgs: []string{
-				"[Error] server responses 401 unauthorized request",
+			wantEvents: []event{
+				{Kind: timehook.EventError, Err: timehook.ErrUnauthorized},
+			},
+		},
+		{
+			name:          "failed on 429 too many requests",
+			given:         func(p *timehook.RegisterAnPollProcess) { p.Connect() },
+			when:          func(p *timehook.RegisterAnPollProcess) { p.Fail(timehook.ErrTooManyRequests) },
+			wantSucceeded: false,
+			wantFinished:  true,
+			wantEvents: []event{
+				{Kind: timehook.EventError, Err: timehook.ErrTooManyRequests},
+			},
+		},
+		{
+			name:          "cancelled while awaiting",
+			given:         func(p *timehook.RegisterAnPollProcess) { p.State(stateAwaiting()) },
+			when:          func(p *timehook.RegisterAnPollProcess) { p.Cancel(context.Canceled) },
+			wantSucceeded: false,
+			wantCancelled: true,
+			wantFinished:  true,
+			wantEvents: []event{
+				{Kind: timehook.EventCancelled, Err: context.Canceled},
 			},
 		},
 		{
@@ -153,8 +197,8 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			when:          func(p *timehook.RegisterAnPollProcess) { p.State(stateSucceededWrongDate()) },
 			wantSucceeded: true,
 			wantFinished:  true,
-			wantMsgs: []string{
-				"\n[0s] webhook succeeded at 2018-01-29 12:32:56\n\n",
+			wantEvents: []event{
+				{Kind: timehook.EventSucceeded, Status: timehook.StatusSucceeded},
 			},
 		},
 	}
@@ -175,25 +219,28 @@ func TestRegisterAnPollProcess(t *testing.T) {
 			v.when(p)
 
 			// then
-			var msgs []string
+			var events []event
 		loop:
 			for {
 				select {
-				case m, ok := <-p.C:
+				case e, ok := <-p.C:
 					if !ok {
 						break loop
 					}
-					msgs = append(msgs, m)
+					events = append(events, newEvent(e))
 				case <-time.After(1 * time.Millisecond):
 					break loop
 				}
 			}
-			if !reflect.DeepEqual(v.wantMsgs, msgs) {
-				t.Errorf("wrong msgs slice: \nwant %#v \ngot  %#v", v.wantMsgs, msgs)
+			if !reflect.DeepEqual(v.wantEvents, events) {
+				t.Errorf("wrong events: \nwant %+v \ngot  %+v", v.wantEvents, events)
 			}
 			if v.wantSucceeded != p.IsSucceeded() {
 				t.Errorf("wrong succeded value, want %v got %v", v.wantSucceeded, p.IsSucceeded())
 			}
+			if v.wantCancelled != p.IsCancelled() {
+				t.Errorf("wrong cancelled value, want %v got %v", v.wantCancelled, p.IsCancelled())
+			}
 			if v.wantFinished != p.IsFinished() {
 				t.Errorf("wrong finished value, want %v got %v", v.wantFinished, p.IsFinished())
 			}
@@ -201,6 +248,132 @@ func TestRegisterAnPollProcess(t *testing.T) {
 	}
 }
 
+func TestRegisterAnPollProcess_Events(t *testing.T) {
+	// given
+	p := timehook.NewRegisterAnPollProcess()
+
+	// when
+	p.State(stateSending())
+	p.State(stateSucceeded())
+
+	// then
+	var events []timehook.Event
+	for e := range p.C {
+		events = append(events, e)
+	}
+	want := []struct {
+		kind    timehook.EventKind
+		time    string
+		elapsed time.Duration
+	}{
+		{kind: timehook.EventConnecting},
+		{kind: timehook.EventScheduled, time: "2018-01-29T12:32:55Z", elapsed: 30 * time.Second},
+		{kind: timehook.EventSending, time: "2018-01-29T12:32:55Z", elapsed: 30 * time.Second},
+		{kind: timehook.EventSucceeded, time: "2018-01-29T12:32:56Z", elapsed: 31 * time.Second},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("wrong number of events want %d got %d", len(want), len(events))
+	}
+	for i, e := range events {
+		if e.Kind != want[i].kind {
+			t.Errorf("wrong kind want %s got %s", want[i].kind, e.Kind)
+		}
+		if e.WebhookID != "the-id" {
+			t.Errorf("wrong webhook ID want %s got %s", "the-id", e.WebhookID)
+		}
+		if want[i].time == "" {
+			continue
+		}
+		if e.Time.UTC().Format(time.RFC3339) != want[i].time {
+			t.Errorf("wrong %s time want %s got %s", e.Kind, want[i].time, e.Time.UTC().Format(time.RFC3339))
+		}
+		if e.Elapsed != want[i].elapsed {
+			t.Errorf("wrong %s elapsed want %s got %s", e.Kind, want[i].elapsed, e.Elapsed)
+		}
+		if e.State == nil {
+			t.Errorf("no state in %s event", e.Kind)
+		}
+	}
+}
+
+func TestRegisterAnPollProcess_QueueCoalesced(t *testing.T) {
+	// given
+	p := timehook.NewRegisterAnPollProcess()
+
+	// when nobody reads C
+	for i := 0; i < 50; i++ {
+		p.State(stateAwaiting())
+	}
+	p.State(stateSucceeded())
+
+	// then C holds 10 events and the queue the last awaiting one
+	var events []timehook.EventKind
+	for e := range p.C {
+		events = append(events, e.Kind)
+	}
+	if len(events) != 13 {
+		t.Fatalf("wrong number of events want %d got %d: %v", 13, len(events), events)
+	}
+	for i, want := range []timehook.EventKind{timehook.EventAwaiting, timehook.EventSending, timehook.EventSucceeded} {
+		if got := events[10+i]; got != want {
+			t.Errorf("wrong event %d want %s got %s", 10+i, want, got)
+		}
+	}
+}
+
+func TestRegisterAnPollProcess_ResultWithoutReading(t *testing.T) {
+	// given
+	goroutines := runtime.NumGoroutine()
+	p := timehook.NewRegisterAnPollProcess()
+	for i := 0; i < 20; i++ {
+		p.State(stateAwaiting())
+	}
+	p.State(stateSucceeded())
+
+	// when
+	res, _ := p.Result()
+
+	// then
+	if res.Outcome != timehook.OutcomeSucceeded {
+		t.Errorf("wrong outcome want %s got %s", timehook.OutcomeSucceeded, res.Outcome)
+	}
+	waitGoroutines(t, goroutines)
+}
+
+// waitGoroutines fails when the goroutines running do not go back to n
+func waitGoroutines(t *testing.T, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked want %d got %d", n, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRegisterAnPollProcess_AfterFinish(t *testing.T) {
+	// given
+	p := timehook.NewRegisterAnPollProcess()
+	p.State(stateSucceeded())
+
+	// when
+	p.Cancel(context.Canceled)
+	p.Error(timehook.ErrUnauthorized)
+	p.State(stateFailed())
+
+	// then
+	res, err := p.Result()
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if res.Outcome != timehook.OutcomeSucceeded {
+		t.Errorf("wrong outcome want %s got %s", timehook.OutcomeSucceeded, res.Outcome)
+	}
+	if p.IsCancelled() {
+		t.Errorf("finished process cancelled")
+	}
+}
+
 func stateRegistered() *timehook.StateResponse {
 	return &timehook.StateResponse{
 		ID:           "the-id",
@@ -0,0 +1,243 @@
+package timehook
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Renderer writes the events of a RegisterAnPollProcess for people to
+// follow it. Close is called after the last event.
+type Renderer interface {
+	Render(e Event) error
+	Close() error
+}
+
+var (
+	_ Renderer = (*LogRenderer)(nil)
+	_ Renderer = (*TTYRenderer)(nil)
+	_ Renderer = (*QuietRenderer)(nil)
+)
+
+// Describe returns a one line description of the event, without the ID of
+// the webhook
+func Describe(e Event) string {
+	switch e.Kind {
+	case EventConnecting:
+		return "connecting to Timehook"
+	case EventScheduled:
+		return fmt.Sprintf("webhook scheduled at %s", e.State.ScheduledAt)
+	case EventAwaiting:
+		return fmt.Sprintf("awaiting the clock to send the webhook at %s", e.State.ScheduledAt)
+	case EventSending:
+		return fmt.Sprintf("sending webhook at %s", e.State.SendingHttpAt)
+	case EventSucceeded:
+		return fmt.Sprintf("webhook succeeded at %s", e.State.SucceededAt)
+	case EventFailed:
+		return fmt.Sprintf("webhook failed at %s", e.State.FailedAt)
+	case EventTimeout:
+		return fmt.Sprintf("webhook timeout at %s", e.State.FailedAt)
+	case EventUnknown:
+		return fmt.Sprintf("unexpected status '%s'", e.State.Status)
+	case EventRetry:
+		return fmt.Sprintf("rate limited, retrying in %s", e.Retry.Delay)
+	case EventError:
+		if !e.IsFinal() {
+			return "rate limited"
+		}
+		return fmt.Sprintf("error: %s", e.Err)
+	case EventCancelled:
+		return fmt.Sprintf("cancelled: %s", e.Err)
+	}
+	return string(e.Kind)
+}
+
+// LogRenderer writes a line per event prefixed with the time it is
+// rendered, suited to logs and output which is not a terminal. Consecutive
+// awaiting events of a webhook are written once.
+type LogRenderer struct {
+	w io.Writer
+
+	mu   sync.Mutex
+	last map[string]EventKind // last event kind by webhook
+}
+
+// NewLogRenderer returns a LogRenderer writing on w
+func NewLogRenderer(w io.Writer) *LogRenderer {
+	return &LogRenderer{w: w, last: make(map[string]EventKind)}
+}
+
+// Render writes the line of the event
+func (r *LogRenderer) Render(e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := r.last[e.WebhookID]
+	r.last[e.WebhookID] = e.Kind
+	if e.Kind == EventAwaiting && last == EventAwaiting {
+		return nil
+	}
+
+	line := time.Now().Format(time.RFC3339) + " "
+	if e.WebhookID != "" {
+		line += e.WebhookID + " "
+	}
+	_, err := io.WriteString(r.w, line+Describe(e)+"\n")
+	return err
+}
+
+// Close does nothing, every line is written on Render
+func (r *LogRenderer) Close() error { return nil }
+
+// QuietRenderer writes only the line of the events finishing a webhook
+type QuietRenderer struct {
+	w io.Writer
+}
+
+// NewQuietRenderer returns a QuietRenderer writing on w
+func NewQuietRenderer(w io.Writer) *QuietRenderer {
+	return &QuietRenderer{w}
+}
+
+// Render writes the line of the event if it finishes the webhook
+func (r *QuietRenderer) Render(e Event) error {
+	if !e.IsFinal() {
+		return nil
+	}
+	line := Describe(e) + "\n"
+	if e.WebhookID != "" {
+		line = e.WebhookID + " " + line
+	}
+	_, err := io.WriteString(r.w, line)
+	return err
+}
+
+// Close does nothing, the line of the last event ends the output
+func (r *QuietRenderer) Close() error { return nil }
+
+// ANSI escape codes of the TTYRenderer
+const (
+	clearLine = "\r\033[K"
+	green     = "\033[32m"
+	red       = "\033[31m"
+	yellow    = "\033[33m"
+	reset     = "\033[0m"
+)
+
+var spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// TTYRenderer writes the events on an interactive terminal as a single line
+// with a spinner and the time elapsed, redrawn while the webhook is pending,
+// and the final status in color
+type TTYRenderer struct {
+	w io.Writer
+
+	mu     sync.Mutex
+	start  time.Time
+	status string
+	frame  int
+	stop   chan struct{} // stops the goroutine redrawing the line, nil if none
+}
+
+// NewTTYRenderer returns a TTYRenderer writing on the terminal w
+func NewTTYRenderer(w io.Writer) *TTYRenderer {
+	return &TTYRenderer{w: w}
+}
+
+// Render redraws the line with the event, or writes the final status when
+// it finishes the webhook
+func (r *TTYRenderer) Render(e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	if e.IsFinal() {
+		r.stopTicking()
+		_, err := fmt.Fprintf(r.w, "%s%s%s %s%s (%s)\n", clearLine, color(e.Kind), symbol(e.Kind), Describe(e), reset, r.elapsed())
+		r.status = ""
+		return err
+	}
+
+	r.status = Describe(e)
+	if r.stop == nil {
+		r.stop = make(chan struct{})
+		go r.tick(r.stop)
+	}
+	return r.draw()
+}
+
+// Close stops redrawing the line and ends it if the webhook did not finish
+func (r *TTYRenderer) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopTicking()
+	if r.status != "" {
+		r.status = ""
+		_, err := io.WriteString(r.w, "\n")
+		return err
+	}
+	return nil
+}
+
+// tick redraws the line until stop is closed
+func (r *TTYRenderer) tick(stop chan struct{}) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		r.mu.Lock()
+		select {
+		case <-stop: // stopped while waiting for the lock
+		default:
+			r.frame++
+			r.draw()
+		}
+		r.mu.Unlock()
+	}
+}
+
+// stopTicking stops the goroutine redrawing the line. It must be called
+// holding the lock.
+func (r *TTYRenderer) stopTicking() {
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// draw writes the line. It must be called holding the lock.
+func (r *TTYRenderer) draw() error {
+	_, err := fmt.Fprintf(r.w, "%s%s %s (%s)", clearLine, spinner[r.frame%len(spinner)], r.status, r.elapsed())
+	return err
+}
+
+func (r *TTYRenderer) elapsed() time.Duration {
+	return time.Since(r.start).Round(time.Second)
+}
+
+func color(kind EventKind) string {
+	switch kind {
+	case EventSucceeded:
+		return green
+	case EventFailed, EventTimeout, EventError:
+		return red
+	}
+	return yellow
+}
+
+func symbol(kind EventKind) string {
+	switch kind {
+	case EventSucceeded:
+		return "✔"
+	case EventFailed, EventTimeout, EventError:
+		return "✘"
+	}
+	return "!"
+}
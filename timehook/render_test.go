@@ -0,0 +1,136 @@
+package timehook_test
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+func events() []timehook.Event {
+	return []timehook.Event{
+		{Kind: timehook.EventConnecting, WebhookID: "the-id"},
+		{Kind: timehook.EventScheduled, WebhookID: "the-id", State: stateRegistered()},
+		{Kind: timehook.EventAwaiting, WebhookID: "the-id", State: stateAwaiting()},
+		{Kind: timehook.EventAwaiting, WebhookID: "the-id", State: stateAwaiting()},
+		{Kind: timehook.EventError, WebhookID: "the-id", Err: timehook.ErrTooManyRequests},
+		{Kind: timehook.EventSending, WebhookID: "the-id", State: stateSending()},
+		{Kind: timehook.EventSucceeded, WebhookID: "the-id", State: stateSucceeded()},
+	}
+}
+
+func TestLogRenderer(t *testing.T) {
+	// given
+	var b bytes.Buffer
+	r := timehook.NewLogRenderer(&b)
+
+	// when
+	for _, e := range events() {
+		r.Render(e)
+	}
+	r.Close()
+
+	// then
+	want := []string{
+		"the-id connecting to Timehook",
+		"the-id webhook scheduled at 2018-01-29T12:32:55+0000",
+		"the-id awaiting the clock to send the webhook at 2018-01-29T12:32:55+0000",
+		"the-id rate limited",
+		"the-id sending webhook at 2018-01-29T12:32:55+0000",
+		"the-id webhook succeeded at 2018-01-29T12:32:56+0000",
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("wrong number of lines want %d got %d:\n%s", len(want), len(lines), b.String())
+	}
+	timestamp := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(Z|[+-]\d\d:\d\d) `)
+	for i, line := range lines {
+		if !timestamp.MatchString(line) {
+			t.Errorf("line without timestamp %q", line)
+		}
+		if got := timestamp.ReplaceAllString(line, ""); got != want[i] {
+			t.Errorf("wrong line %d want %q got %q", i, want[i], got)
+		}
+	}
+}
+
+func TestQuietRenderer(t *testing.T) {
+	// given
+	var b bytes.Buffer
+	r := timehook.NewQuietRenderer(&b)
+
+	// when
+	for _, e := range events() {
+		r.Render(e)
+	}
+	r.Close()
+
+	// then
+	want := "the-id webhook succeeded at 2018-01-29T12:32:56+0000\n"
+	if b.String() != want {
+		t.Errorf("wrong output want %q got %q", want, b.String())
+	}
+}
+
+func TestTTYRenderer(t *testing.T) {
+	// given
+	var b bytes.Buffer
+	r := timehook.NewTTYRenderer(&b)
+
+	// when
+	for _, e := range events() {
+		r.Render(e)
+	}
+	r.Close()
+
+	// then
+	out := b.String()
+	if strings.Count(out, "\n") != 1 || !strings.HasSuffix(out, "\n") {
+		t.Errorf("output expected in a single line, got %q", out)
+	}
+	if !strings.Contains(out, "\r\033[K") {
+		t.Errorf("line expected to be redrawn, got %q", out)
+	}
+	final := out[strings.LastIndex(out, "\r"):]
+	if !strings.Contains(final, "\033[32m") || !strings.Contains(final, "webhook succeeded at 2018-01-29T12:32:56+0000") {
+		t.Errorf("wrong final status %q", final)
+	}
+}
+
+func TestTTYRenderer_CloseUnfinished(t *testing.T) {
+	// given
+	var b bytes.Buffer
+	r := timehook.NewTTYRenderer(&b)
+	r.Render(timehook.Event{Kind: timehook.EventConnecting})
+
+	// when
+	r.Close()
+
+	// then
+	if !strings.HasSuffix(b.String(), "\n") {
+		t.Errorf("line expected to be ended, got %q", b.String())
+	}
+}
+
+func TestEvent_IsFinal(t *testing.T) {
+	tt := []struct {
+		given timehook.Event
+		want  bool
+	}{
+		{given: timehook.Event{Kind: timehook.EventAwaiting}, want: false},
+		{given: timehook.Event{Kind: timehook.EventRetry}, want: false},
+		{given: timehook.Event{Kind: timehook.EventSucceeded}, want: true},
+		{given: timehook.Event{Kind: timehook.EventCancelled}, want: true},
+		{given: timehook.Event{Kind: timehook.EventError, Err: errors.New("boom")}, want: true},
+		{given: timehook.Event{Kind: timehook.EventError, Err: timehook.ErrTooManyRequests}, want: false},
+	}
+
+	for _, tc := range tt {
+		if got := tc.given.IsFinal(); got != tc.want {
+			t.Errorf("wrong IsFinal of %s %v want %t got %t", tc.given.Kind, tc.given.Err, tc.want, got)
+		}
+	}
+}
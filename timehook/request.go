@@ -0,0 +1,118 @@
+package timehook
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// RegisterRequest describes a webhook to register
+type RegisterRequest struct {
+	// URL is where the webhook is sent
+	URL string
+	// Body is the body of the webhook, nil for an empty one. It is streamed
+	// to the API, so it can be a file or the standard input, and it is not
+	// closed by the client.
+	Body io.Reader
+	// Delay is the time from the registration until the webhook is sent,
+	// rounded up to seconds
+	Delay time.Duration
+	// At is when the webhook is sent, in any time zone. It is an alternative
+	// to Delay and must be in the future.
+	At time.Time
+	// Method is the HTTP method of the webhook, POST when empty
+	Method string
+	// ContentType is the media type of the body, application/json when empty
+	ContentType string
+	// Headers are forwarded to the webhook target. They are sent to the API
+	// prefixed with HeaderPrefix to keep them apart from the API headers.
+	// Content-Type is set with ContentType instead.
+	Headers http.Header
+}
+
+// HeaderPrefix prefixes the headers sent to the API which are forwarded to
+// the webhook target
+const HeaderPrefix = "X-Webhook-Header-"
+
+// setHeaders sets the headers to forward in the request to the API
+func (r *RegisterRequest) setHeaders(req *http.Request) error {
+	for name, values := range r.Headers {
+		if http.CanonicalHeaderKey(name) == "Content-Type" {
+			return errors.New("Content-Type header must be set with ContentType")
+		}
+		for _, v := range values {
+			req.Header.Add(HeaderPrefix+name, v)
+		}
+	}
+	return nil
+}
+
+// contentType returns the media type of the body
+func (r *RegisterRequest) contentType() string {
+	if r.ContentType == "" {
+		return "application/json"
+	}
+	return r.ContentType
+}
+
+// ErrPastSchedule is returned registering a webhook scheduled in the past
+var ErrPastSchedule = errors.New("webhook scheduled in the past")
+
+// seconds returns the delay in seconds from now until the webhook is sent as
+// expected by the API, rounded up
+func (r *RegisterRequest) seconds(now time.Time) (int, error) {
+	d := r.Delay
+	if !r.At.IsZero() {
+		if d != 0 {
+			return 0, errors.New("webhook scheduled with both Delay and At")
+		}
+		d = r.At.Sub(now)
+	}
+	if d < 0 {
+		return 0, ErrPastSchedule
+	}
+
+	sec := d / time.Second
+	if d%time.Second > 0 {
+		sec++
+	}
+	return int(sec), nil
+}
+
+// setBody makes the request send body without closing it, as it belongs to
+// the caller. Seekable bodies, as files are, can be sent again when the
+// request is retried and their length is known. Other readers are streamed
+// once.
+func setBody(req *http.Request, body io.Reader) error {
+	if body == nil || req.GetBody != nil { // already rewindable by net/http
+		return nil
+	}
+	req.Body = ioutil.NopCloser(body)
+
+	s, ok := body.(io.ReadSeeker)
+	if !ok {
+		return nil
+	}
+	offset, err := s.Seek(0, io.SeekCurrent)
+	if err != nil { // not seekable, e.g. a pipe
+		return nil
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("can not seek body: %s", err)
+	}
+	if _, err := s.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("can not seek body: %s", err)
+	}
+
+	req.ContentLength = end - offset
+	req.GetBody = func() (io.ReadCloser, error) {
+		_, err := s.Seek(offset, io.SeekStart)
+		return ioutil.NopCloser(s), err
+	}
+
+	return nil
+}
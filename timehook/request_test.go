@@ -0,0 +1,302 @@
+package timehook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/mock"
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestRegister_FileBody(t *testing.T) {
+	// given
+	f, err := ioutil.TempFile("", "body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.WriteString(`{"foo" : "bar"}`)
+	f.Seek(0, io.SeekStart)
+
+	HTTPClient := mock.HTTPClient([]interface{}{mock.TooManyRequest429(), mock.RegisteredSuccess()})
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+
+	// when
+	_, err = client.Register(context.Background(), &timehook.RegisterRequest{URL: "https://the-domain.com", Body: f})
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	req := HTTPClient.Spies()[1]
+	if req.ContentLength != 15 {
+		t.Errorf("wrong content length want %d got %d", 15, req.ContentLength)
+	}
+	b, _ := ioutil.ReadAll(req.Body)
+	if string(b) != `{"foo" : "bar"}` {
+		t.Errorf("wrong body on retry want %s got %s", `{"foo" : "bar"}`, b)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Errorf("body closed by the client: %s", err)
+	}
+}
+
+func TestRegister_StreamedBodyNotRetried(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.TooManyRequest429()})
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+	body := io.MultiReader(strings.NewReader(`{"foo" `), strings.NewReader(`: "bar"}`))
+
+	// when
+	_, err := client.Register(context.Background(), &timehook.RegisterRequest{URL: "https://the-domain.com", Body: body})
+
+	// then
+	if !errors.Is(err, timehook.ErrTooManyRequests) {
+		t.Errorf("wrong error want %s got %v", timehook.ErrTooManyRequests, err)
+	}
+	if len(HTTPClient.Spies()) != 1 {
+		t.Errorf("wrong number of requests want %d got %d", 1, len(HTTPClient.Spies()))
+	}
+}
+
+func TestRegister_DelayInSeconds(t *testing.T) {
+	tt := []struct {
+		given time.Duration
+		want  string
+	}{
+		{given: 0, want: "0"},
+		{given: 5 * time.Second, want: "5"},
+		{given: 1500 * time.Millisecond, want: "2"},
+		{given: 2 * time.Hour, want: "7200"},
+	}
+
+	for _, v := range tt {
+		t.Run(v.given.String(), func(t *testing.T) {
+			HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess()})
+			client := timehook.New("api-key", HTTPClient)
+
+			_, err := client.Register(context.Background(), &timehook.RegisterRequest{URL: "https://the-domain.com", Delay: v.given})
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := HTTPClient.Spies()[0].Header.Get("X-Seconds"); got != v.want {
+				t.Errorf("wrong header X-Seconds want %s got %s", v.want, got)
+			}
+		})
+	}
+}
+
+func TestRegister_At(t *testing.T) {
+	madrid := time.FixedZone("CET", 3600)
+	tt := []struct {
+		name    string
+		given   *timehook.RegisterRequest
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "in one hour",
+			given: &timehook.RegisterRequest{URL: "https://the-domain.com", At: time.Now().Add(1 * time.Hour)},
+			want:  "3600",
+		},
+		{
+			name:  "in one hour in another time zone",
+			given: &timehook.RegisterRequest{URL: "https://the-domain.com", At: time.Now().Add(1 * time.Hour).In(madrid)},
+			want:  "3600",
+		},
+		{
+			name:    "in the past",
+			given:   &timehook.RegisterRequest{URL: "https://the-domain.com", At: time.Now().Add(-1 * time.Minute)},
+			wantErr: timehook.ErrPastSchedule,
+		},
+		{
+			name:    "negative delay",
+			given:   &timehook.RegisterRequest{URL: "https://the-domain.com", Delay: -1 * time.Second},
+			wantErr: timehook.ErrPastSchedule,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess()})
+			client := timehook.New("api-key", HTTPClient)
+
+			_, err := client.Register(context.Background(), v.given)
+
+			if v.wantErr != nil {
+				if err != v.wantErr {
+					t.Errorf("wrong error want %s got %v", v.wantErr, err)
+				}
+				if len(HTTPClient.Spies()) != 0 {
+					t.Errorf("wrong number of requests want %d got %d", 0, len(HTTPClient.Spies()))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := HTTPClient.Spies()[0].Header.Get("X-Seconds"); got != v.want {
+				t.Errorf("wrong header X-Seconds want %s got %s", v.want, got)
+			}
+		})
+	}
+}
+
+// secondsRecorder records the X-Seconds header of every request, as the
+// requests retried are the same one
+type secondsRecorder struct {
+	timehook.HTTPDoer
+	seconds []int
+}
+
+func (r *secondsRecorder) Do(req *http.Request) (*http.Response, error) {
+	sec, _ := strconv.Atoi(req.Header.Get("X-Seconds"))
+	r.seconds = append(r.seconds, sec)
+	return r.HTTPDoer.Do(req)
+}
+
+func TestRegister_AtRetried(t *testing.T) {
+	// given
+	limited := mock.TooManyRequest429()
+	limited.Header.Set("Retry-After", "1")
+	HTTPClient := &secondsRecorder{HTTPDoer: mock.HTTPClient([]interface{}{limited, mock.RegisteredSuccess()})}
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: 5 * time.Second}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+
+	// when
+	_, err := client.Register(context.Background(), &timehook.RegisterRequest{
+		URL: "https://the-domain.com",
+		At:  time.Now().Add(1 * time.Minute),
+	})
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(HTTPClient.seconds) != 2 {
+		t.Fatalf("wrong number of requests want %d got %d", 2, len(HTTPClient.seconds))
+	}
+	if first, second := HTTPClient.seconds[0], HTTPClient.seconds[1]; second >= first || second < first-3 {
+		t.Errorf("X-Seconds not counted again on retry, first %d second %d", first, second)
+	}
+}
+
+func TestRegister_MethodAndContentType(t *testing.T) {
+	tt := []struct {
+		name            string
+		given           *timehook.RegisterRequest
+		wantMethod      string
+		wantContentType string
+	}{
+		{
+			name:            "defaults",
+			given:           &timehook.RegisterRequest{URL: "https://the-domain.com"},
+			wantMethod:      "",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "put plain text",
+			given:           &timehook.RegisterRequest{URL: "https://the-domain.com", Method: "put", ContentType: "text/plain"},
+			wantMethod:      "PUT",
+			wantContentType: "text/plain",
+		},
+		{
+			name:            "patch form",
+			given:           &timehook.RegisterRequest{URL: "https://the-domain.com", Method: "PATCH", ContentType: "application/x-www-form-urlencoded"},
+			wantMethod:      "PATCH",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess()})
+			client := timehook.New("api-key", HTTPClient)
+
+			_, err := client.Register(context.Background(), v.given)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			req := HTTPClient.Spies()[0]
+			if req.Method != "POST" {
+				t.Errorf("wrong method to register want %s got %s", "POST", req.Method)
+			}
+			if got := req.Header.Get("X-Method"); got != v.wantMethod {
+				t.Errorf("wrong header X-Method want %q got %q", v.wantMethod, got)
+			}
+			if got := req.Header.Get("Content-Type"); got != v.wantContentType {
+				t.Errorf("wrong header Content-Type want %s got %s", v.wantContentType, got)
+			}
+		})
+	}
+}
+
+func TestRegister_Headers(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess()})
+	client := timehook.New("api-key", HTTPClient)
+	r := registerRequest()
+	r.Headers = http.Header{}
+	r.Headers.Set("Authorization", "Bearer target-token")
+	r.Headers.Add("X-Request-ID", "1")
+	r.Headers.Add("X-Request-ID", "2")
+
+	// when
+	_, err := client.Register(context.Background(), r)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	req := HTTPClient.Spies()[0]
+	if got := req.Header.Get("Authorization"); got != "Bearer api-key" {
+		t.Errorf("wrong header Authorization want %s got %s", "Bearer api-key", got)
+	}
+	if got := req.Header.Get("X-Webhook-Header-Authorization"); got != "Bearer target-token" {
+		t.Errorf("wrong header X-Webhook-Header-Authorization want %s got %s", "Bearer target-token", got)
+	}
+	if got := req.Header["X-Webhook-Header-X-Request-Id"]; !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("wrong header X-Webhook-Header-X-Request-Id want %v got %v", []string{"1", "2"}, got)
+	}
+}
+
+func TestRegister_ContentTypeHeader(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{})
+	client := timehook.New("api-key", HTTPClient)
+	r := registerRequest()
+	r.Headers = http.Header{"content-type": []string{"text/plain"}}
+
+	// when
+	_, err := client.Register(context.Background(), r)
+
+	// then
+	if err == nil {
+		t.Errorf("expected error forwarding Content-Type")
+	}
+	if len(HTTPClient.Spies()) != 0 {
+		t.Errorf("wrong number of requests want %d got %d", 0, len(HTTPClient.Spies()))
+	}
+}
+
+func registerRequest() *timehook.RegisterRequest {
+	return &timehook.RegisterRequest{
+		URL:   "https://the-domain.com",
+		Body:  strings.NewReader(`{"foo" : "bar"}`),
+		Delay: 5 * time.Second,
+	}
+}
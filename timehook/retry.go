@@ -0,0 +1,137 @@
+package timehook
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how requests answered with 429 Too Many Requests are
+// retried. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first request
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on each retry
+	BaseDelay time.Duration
+	// MaxDelay bounds the backoff. Requests asked by Retry-After to wait
+	// longer are not retried.
+	MaxDelay time.Duration
+	// Jitter is the fraction, from 0 to 1, of the delay randomized
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the policy used by clients created without
+// WithRetryPolicy
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+// WithRetryPolicy sets the policy used to retry requests rate limited by the
+// server
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// Retry describes a request rate limited by the server which is about to be
+// retried
+type Retry struct {
+	Attempt  int           // the attempt rate limited, starting at 1
+	Delay    time.Duration // time to wait before the next attempt
+	Endpoint string        // method and URL of the request
+	Err      error
+}
+
+type retryNotifyKey struct{}
+
+// ContextWithRetryNotify returns a copy of ctx which makes the client call fn
+// before retrying any request executed with it
+func ContextWithRetryNotify(ctx context.Context, fn func(Retry)) context.Context {
+	return context.WithValue(ctx, retryNotifyKey{}, fn)
+}
+
+func notifyRetry(ctx context.Context, r Retry) {
+	if fn, ok := ctx.Value(retryNotifyKey{}).(func(Retry)); ok {
+		fn(r)
+	}
+}
+
+// delay returns how long to wait after the attempt given. The Retry-After
+// header of the response, in seconds or as HTTP date, takes precedence over
+// the exponential backoff, and it returns false when it asks to wait longer
+// than MaxDelay.
+func (p RetryPolicy) delay(attempt int, header http.Header) (time.Duration, bool) {
+	if d, ok := retryAfter(header.Get("Retry-After")); ok {
+		if d < 0 {
+			d = 0
+		}
+		return d, d <= p.MaxDelay
+	}
+
+	d := p.BaseDelay << uint(attempt-1)
+	if d <= 0 || d > p.MaxDelay { // shift overflow
+		d = p.MaxDelay
+	}
+	d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// waits returns if ctx lets wait for d before its deadline
+func waits(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+// rateLimitDelay returns how long the server asks to wait in Retry-After
+// when err is a request rate limited, zero otherwise. Polling waits for it
+// once the request is not retried anymore, rather than querying again on the
+// next tick.
+func rateLimitDelay(err error) time.Duration {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.Is(ErrTooManyRequests) {
+		return 0
+	}
+	if d, ok := retryAfter(apiErr.Header.Get("Retry-After")); ok && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// retryAfter parses the value of a Retry-After header
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(sec) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
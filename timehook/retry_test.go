@@ -0,0 +1,275 @@
+package timehook_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/mock"
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestRegister_RetryTooManyRequests(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.TooManyRequest429(),
+		mock.TooManyRequest429(),
+		mock.RegisteredSuccess(),
+	})
+	policy := timehook.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+	var retries []timehook.Retry
+	ctx := timehook.ContextWithRetryNotify(context.Background(), func(r timehook.Retry) {
+		retries = append(retries, r)
+	})
+
+	// when
+	rr, err := client.Register(ctx, registerRequest())
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rr.ID != "9e9480a4-271b-4708-993a-064509457a23" {
+		t.Errorf("wrong ID want %s got %s", "9e9480a4-271b-4708-993a-064509457a23", rr.ID)
+	}
+	if len(retries) != 2 {
+		t.Fatalf("wrong number of retries want %d got %d", 2, len(retries))
+	}
+	for i, r := range retries {
+		if r.Attempt != i+1 {
+			t.Errorf("wrong attempt want %d got %d", i+1, r.Attempt)
+		}
+		if r.Endpoint != "POST https://api.timehook.io/webhooks" {
+			t.Errorf("wrong endpoint want %s got %s", "POST https://api.timehook.io/webhooks", r.Endpoint)
+		}
+		if !errors.Is(r.Err, timehook.ErrTooManyRequests) {
+			t.Errorf("wrong error want %s got %s", timehook.ErrTooManyRequests, r.Err)
+		}
+	}
+	last := HTTPClient.Spies()[2]
+	b, _ := ioutil.ReadAll(last.Body)
+	if string(b) != `{"foo" : "bar"}` {
+		t.Errorf("wrong body on retry want %s got %s", `{"foo" : "bar"}`, b)
+	}
+}
+
+func TestRegister_RetryExhausted(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.TooManyRequest429(),
+		mock.TooManyRequest429(),
+	})
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+
+	// when
+	_, err := client.Register(context.Background(), registerRequest())
+
+	// then
+	if !errors.Is(err, timehook.ErrTooManyRequests) {
+		t.Errorf("wrong error want %s got %v", timehook.ErrTooManyRequests, err)
+	}
+	if len(HTTPClient.Spies()) != 2 {
+		t.Errorf("wrong number of requests want %d got %d", 2, len(HTTPClient.Spies()))
+	}
+}
+
+func TestState_RetryAfter(t *testing.T) {
+	// given
+	limited := mock.TooManyRequest429()
+	limited.Header.Set("Retry-After", "1")
+	HTTPClient := mock.HTTPClient([]interface{}{limited, mock.StateAwaiting()})
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: 5 * time.Second}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+	var retries []timehook.Retry
+	ctx := timehook.ContextWithRetryNotify(context.Background(), func(r timehook.Retry) {
+		retries = append(retries, r)
+	})
+
+	// when
+	start := time.Now()
+	_, err := client.State(ctx, "9e9480a4-271b-4708-993a-064509457a23")
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(retries) != 1 {
+		t.Fatalf("wrong number of retries want %d got %d", 1, len(retries))
+	}
+	if retries[0].Delay != 1*time.Second {
+		t.Errorf("wrong delay want %s got %s", 1*time.Second, retries[0].Delay)
+	}
+	if elapsed := time.Since(start); elapsed < 1*time.Second {
+		t.Errorf("retried before Retry-After, after %s", elapsed)
+	}
+}
+
+func TestState_RetryAfterTooLong(t *testing.T) {
+	tt := []struct {
+		name     string
+		maxDelay time.Duration
+		timeout  time.Duration
+	}{
+		{name: "longer than max delay", maxDelay: 30 * time.Second},
+		{name: "after the deadline", maxDelay: 2 * time.Minute, timeout: 1 * time.Second},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			// given
+			limited := mock.TooManyRequest429()
+			limited.Header.Set("Retry-After", "60")
+			HTTPClient := mock.HTTPClient([]interface{}{limited, mock.StateAwaiting()})
+			policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: v.maxDelay}
+			client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+			ctx := context.Background()
+			if v.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, v.timeout)
+				defer cancel()
+			}
+
+			// when
+			_, err := client.State(ctx, "9e9480a4-271b-4708-993a-064509457a23")
+
+			// then
+			if !errors.Is(err, timehook.ErrTooManyRequests) {
+				t.Errorf("error expected %v got %v", timehook.ErrTooManyRequests, err)
+			}
+			if len(HTTPClient.Spies()) != 1 {
+				t.Errorf("wrong number of requests want %d got %d", 1, len(HTTPClient.Spies()))
+			}
+		})
+	}
+}
+
+func TestRegisterAndPoll_RetryRegister(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{
+		mock.TooManyRequest429(),
+		mock.RegisteredSuccess(),
+		mock.StateSucceeded(),
+	})
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+
+	// when
+	proc := client.RegisterAndPoll("https://the-domain.com", `{"foo" : "bar"}`, 5, 1*time.Nanosecond)
+	for range proc.C {
+	}
+
+	// then
+	if !proc.IsSucceeded() {
+		t.Errorf("process not succeeded")
+	}
+}
+
+func TestRegisterAndPoll_RetryRegisterExhausted(t *testing.T) {
+	limited := mock.TooManyRequest429()
+	limited.Header.Set("Retry-After", "60")
+	tt := []struct {
+		name      string
+		responses []interface{}
+		policy    timehook.RetryPolicy
+	}{
+		{
+			name:      "no retries",
+			responses: []interface{}{mock.TooManyRequest429()},
+		},
+		{
+			name:      "attempts exhausted",
+			responses: []interface{}{mock.TooManyRequest429(), mock.TooManyRequest429()},
+			policy:    timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond},
+		},
+		{
+			name:      "retry after too long",
+			responses: []interface{}{limited},
+			policy:    timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			client := timehook.New("api-key", mock.HTTPClient(tc.responses), timehook.WithRetryPolicy(tc.policy))
+
+			// when
+			proc := client.RegisterAndPoll("https://the-domain.com", `{"foo" : "bar"}`, 5, 1*time.Nanosecond)
+			select {
+			case <-proc.Done():
+			case <-time.After(time.Second):
+				t.Fatal("process not finished")
+			}
+			var last timehook.Event
+			for e := range proc.C {
+				last = e
+			}
+			res, err := proc.Result()
+
+			// then
+			if res.Outcome != timehook.OutcomeError {
+				t.Errorf("wrong outcome want %s got %s", timehook.OutcomeError, res.Outcome)
+			}
+			if !errors.Is(err, timehook.ErrTooManyRequests) {
+				t.Errorf("wrong error want %s got %v", timehook.ErrTooManyRequests, err)
+			}
+			if last.Kind != timehook.EventError || !last.IsFinal() {
+				t.Errorf("final error event expected, got %+v", last)
+			}
+		})
+	}
+}
+
+// rateLimited returns n responses 429 asking to wait a minute
+func rateLimited(n int) []interface{} {
+	var responses []interface{}
+	for i := 0; i < n; i++ {
+		resp := mock.TooManyRequest429()
+		resp.Header.Set("Retry-After", "60")
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestWatch_RetryAfterTooLong(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient(rateLimited(20))
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// when
+	res, _ := client.Watch(ctx, "9e9480a4-271b-4708-993a-064509457a23", 50*time.Millisecond).Result()
+
+	// then
+	if res.Outcome != timehook.OutcomeCancelled {
+		t.Errorf("wrong outcome want %s got %s", timehook.OutcomeCancelled, res.Outcome)
+	}
+	if len(HTTPClient.Spies()) != 1 {
+		t.Errorf("wrong number of requests want %d got %d", 1, len(HTTPClient.Spies()))
+	}
+}
+
+func TestWatchGroup_RetryAfterTooLong(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient(rateLimited(20))
+	policy := timehook.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Nanosecond, MaxDelay: time.Millisecond}
+	client := timehook.New("api-key", HTTPClient, timehook.WithRetryPolicy(policy))
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// when
+	res := client.WatchGroup(ctx, []string{"first", "second"}, 50*time.Millisecond).Result()
+
+	// then
+	if res.Counts[timehook.OutcomeCancelled] != 2 {
+		t.Errorf("wrong counts %v", res.Counts)
+	}
+	if len(HTTPClient.Spies()) != 1 {
+		t.Errorf("wrong number of requests want %d got %d", 1, len(HTTPClient.Spies()))
+	}
+}
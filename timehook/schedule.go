@@ -0,0 +1,61 @@
+package timehook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Scheduler computes the times a recurring webhook is sent. Next returns the
+// first time after t, or the zero time when there is none. It is implemented
+// by cron.Schedule.
+type Scheduler interface {
+	Next(t time.Time) time.Time
+}
+
+// Occurrence is a webhook registered for a time of a Scheduler
+type Occurrence struct {
+	At time.Time
+	*RegisterResponse
+}
+
+// RegisterSchedule registers the webhook described by r for each of the next
+// n times s fires after from, as Timehook sends every webhook once. r must
+// not have Delay nor At, they are set by the schedule. The body is read once
+// and sent with every occurrence.
+//
+// It returns the occurrences registered, which are all the scheduled unless
+// an error stops it.
+func (c *Client) RegisterSchedule(ctx context.Context, r *RegisterRequest, s Scheduler, from time.Time, n int) ([]Occurrence, error) {
+	if r.Delay != 0 || !r.At.IsZero() {
+		return nil, errors.New("scheduled webhook must not have Delay nor At")
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, fmt.Errorf("can not read body: %s", err)
+		}
+	}
+
+	var occs []Occurrence
+	for t := s.Next(from); len(occs) < n && !t.IsZero(); t = s.Next(t) {
+		o := *r
+		o.At = t
+		if r.Body != nil {
+			o.Body = bytes.NewReader(body)
+		}
+
+		rr, err := c.Register(ctx, &o)
+		if err != nil {
+			return occs, fmt.Errorf("can not register webhook at %s: %w", t.Format(time.RFC3339), err)
+		}
+		occs = append(occs, Occurrence{At: t, RegisterResponse: rr})
+	}
+
+	return occs, nil
+}
@@ -0,0 +1,126 @@
+package timehook_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/mock"
+	"github.com/timehook/cli-client/timehook"
+)
+
+// hourly fires every hour o'clock
+type hourly struct{}
+
+func (hourly) Next(t time.Time) time.Time { return t.Truncate(time.Hour).Add(time.Hour) }
+
+// once fires only at the time given
+type once time.Time
+
+func (o once) Next(t time.Time) time.Time {
+	if t.Before(time.Time(o)) {
+		return time.Time(o)
+	}
+	return time.Time{}
+}
+
+func TestRegisterSchedule(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess(), mock.RegisteredSuccess(), mock.RegisteredSuccess()})
+	client := timehook.New("api-key", HTTPClient)
+	r := registerRequest()
+	r.Delay = 0
+	r.Body = strings.NewReader(`{"foo" : "bar"}`)
+	from := time.Now()
+
+	// when
+	occs, err := client.RegisterSchedule(context.Background(), r, hourly{}, from, 3)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(occs) != 3 {
+		t.Fatalf("wrong number of occurrences want %d got %d", 3, len(occs))
+	}
+	for i, o := range occs {
+		want := from.Truncate(time.Hour).Add(time.Duration(i+1) * time.Hour)
+		if !o.At.Equal(want) {
+			t.Errorf("wrong time of occurrence %d want %s got %s", i, want, o.At)
+		}
+		if o.RegisterResponse == nil || o.ID == "" {
+			t.Errorf("occurrence %d without registration", i)
+		}
+
+		req := HTTPClient.Spies()[i]
+		sec, _ := strconv.Atoi(req.Header.Get("X-Seconds"))
+		if d := time.Duration(sec)*time.Second - want.Sub(from); d < -time.Second || d > time.Second {
+			t.Errorf("wrong X-Seconds of occurrence %d: %d for %s", i, sec, want.Sub(from))
+		}
+		body, _ := req.GetBody()
+		if b, _ := ioutil.ReadAll(body); string(b) != `{"foo" : "bar"}` {
+			t.Errorf("wrong body of occurrence %d want %s got %s", i, `{"foo" : "bar"}`, b)
+		}
+	}
+}
+
+func TestRegisterSchedule_EndsBeforeN(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess()})
+	client := timehook.New("api-key", HTTPClient)
+	r := registerRequest()
+	r.Delay = 0
+
+	// when
+	occs, err := client.RegisterSchedule(context.Background(), r, once(time.Now().Add(time.Hour)), time.Now(), 5)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(occs) != 1 {
+		t.Errorf("wrong number of occurrences want %d got %d", 1, len(occs))
+	}
+}
+
+func TestRegisterSchedule_Error(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{mock.RegisteredSuccess(), mock.Unauthorized()})
+	client := timehook.New("api-key", HTTPClient)
+	r := registerRequest()
+	r.Delay = 0
+
+	// when
+	occs, err := client.RegisterSchedule(context.Background(), r, hourly{}, time.Now(), 3)
+
+	// then
+	if !errors.Is(err, timehook.ErrUnauthorized) {
+		t.Errorf("wrong error want %s got %v", timehook.ErrUnauthorized, err)
+	}
+	if len(occs) != 1 {
+		t.Errorf("wrong number of occurrences want %d got %d", 1, len(occs))
+	}
+}
+
+func TestRegisterSchedule_WithDelay(t *testing.T) {
+	// given
+	HTTPClient := mock.HTTPClient([]interface{}{})
+	client := timehook.New("api-key", HTTPClient)
+	r := registerRequest()
+	r.Delay = time.Minute
+
+	// when
+	_, err := client.RegisterSchedule(context.Background(), r, hourly{}, time.Now(), 3)
+
+	// then
+	if err == nil {
+		t.Error("error expected")
+	}
+	if len(HTTPClient.Spies()) != 0 {
+		t.Errorf("wrong number of requests want %d got %d", 0, len(HTTPClient.Spies()))
+	}
+}
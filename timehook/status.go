@@ -0,0 +1,38 @@
+package timehook
+
+// Status is the state of a webhook in the Timehook API
+type Status string
+
+const (
+	StatusRegistered    Status = "registered"
+	StatusAwaitingClock Status = "awaitingClock"
+	StatusSendingHTTP   Status = "sendingHttp"
+	StatusSucceeded     Status = "succeeded"
+	StatusFailed        Status = "failed"
+	StatusTimeout       Status = "timeout"
+)
+
+// IsKnown returns if the status is one of the statuses defined by the API
+func (s Status) IsKnown() bool {
+	switch s {
+	case StatusRegistered, StatusAwaitingClock, StatusSendingHTTP, StatusSucceeded, StatusFailed, StatusTimeout:
+		return true
+	}
+	return false
+}
+
+// IsFinal returns if the webhook will not change its status anymore
+func (s Status) IsFinal() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusTimeout
+}
+
+// IsSuccess returns if the webhook finished and succeeded
+func (s Status) IsSuccess() bool {
+	return s == StatusSucceeded
+}
+
+// IsFinal returns if the webhook will not change its status anymore
+func (s *StateResponse) IsFinal() bool { return s.Status.IsFinal() }
+
+// IsSuccess returns if the webhook finished and succeeded
+func (s *StateResponse) IsSuccess() bool { return s.Status.IsSuccess() }
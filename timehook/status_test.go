@@ -0,0 +1,38 @@
+package timehook_test
+
+import (
+	"testing"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestStatus(t *testing.T) {
+	tt := []struct {
+		given       timehook.Status
+		wantKnown   bool
+		wantFinal   bool
+		wantSuccess bool
+	}{
+		{given: timehook.StatusRegistered, wantKnown: true},
+		{given: timehook.StatusAwaitingClock, wantKnown: true},
+		{given: timehook.StatusSendingHTTP, wantKnown: true},
+		{given: timehook.StatusSucceeded, wantKnown: true, wantFinal: true, wantSuccess: true},
+		{given: timehook.StatusFailed, wantKnown: true, wantFinal: true},
+		{given: timehook.StatusTimeout, wantKnown: true, wantFinal: true},
+		{given: "unknown-status"},
+	}
+
+	for _, v := range tt {
+		t.Run(string(v.given), func(t *testing.T) {
+			if v.given.IsKnown() != v.wantKnown {
+				t.Errorf("wrong known value, want %v got %v", v.wantKnown, v.given.IsKnown())
+			}
+			if v.given.IsFinal() != v.wantFinal {
+				t.Errorf("wrong final value, want %v got %v", v.wantFinal, v.given.IsFinal())
+			}
+			if v.given.IsSuccess() != v.wantSuccess {
+				t.Errorf("wrong success value, want %v got %v", v.wantSuccess, v.given.IsSuccess())
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package timehook
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// layouts are the ISO 8601 variants the API may emit. Fractional seconds are
+// accepted by all of them.
+var layouts = []string{
+	"2006-01-02T15:04:05Z0700",
+	time.RFC3339,
+	"2006-01-02T15:04:05Z07",
+	"20060102T150405Z0700",
+}
+
+// ParseTime parses a date in any ISO 8601 variant the API may emit: offsets
+// as Z, +hh:mm, +hhmm or +hh, with or without fractional seconds and in
+// extended or basic format. Dates without offset are taken as UTC.
+func ParseTime(s string) (time.Time, error) {
+	for _, l := range layouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05", s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("can not parse date %q as ISO 8601", s)
+}
+
+// ParseTimeIn parses a date given by a user, in any ISO 8601 variant
+// ParseTime accepts, with seconds optional and a space instead of T, e.g.
+// "2026-11-01 09:00". Dates without offset are in loc.
+func ParseTimeIn(s string, loc *time.Location) (time.Time, error) {
+	v := strings.Replace(s, " ", "T", 1)
+	for _, l := range append(layouts, "2006-01-02T15:04Z07:00") {
+		if t, err := time.Parse(l, v); err == nil {
+			return t, nil
+		}
+	}
+	for _, l := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(l, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can not parse date %q as ISO 8601, e.g. 2006-01-02T15:04:05+02:00", s)
+}
+
+// Durations splits the life of a webhook in its stages
+type Durations struct {
+	// Queue is the time from being registered until awaiting the clock
+	Queue time.Duration
+	// Wait is the time from awaiting the clock until sending the webhook
+	Wait time.Duration
+	// Delivery is the time from sending the webhook until it succeeded,
+	// failed or timed out
+	Delivery time.Duration
+}
+
+// RegisteredTime returns RegisteredAt parsed or the zero time when invalid
+func (s *StateResponse) RegisteredTime() time.Time { return parseTimeOrZero(s.RegisteredAt) }
+
+// ScheduledTime returns ScheduledAt parsed or the zero time when invalid
+func (s *StateResponse) ScheduledTime() time.Time { return parseTimeOrZero(s.ScheduledAt) }
+
+// AwaitingClockTime returns AwaitingClockAt parsed or the zero time when
+// invalid
+func (s *StateResponse) AwaitingClockTime() time.Time { return parseTimeOrZero(s.AwaitingClockAt) }
+
+// SendingHTTPTime returns SendingHttpAt parsed or the zero time when invalid
+func (s *StateResponse) SendingHTTPTime() time.Time { return parseTimeOrZero(s.SendingHttpAt) }
+
+// SucceededTime returns SucceededAt parsed or the zero time when invalid
+func (s *StateResponse) SucceededTime() time.Time { return parseTimeOrZero(s.SucceededAt) }
+
+// FailedTime returns FailedAt parsed or the zero time when invalid
+func (s *StateResponse) FailedTime() time.Time { return parseTimeOrZero(s.FailedAt) }
+
+// Durations returns the time spent by the webhook in each stage. Stages not
+// reached yet or with invalid dates are zero.
+func (s *StateResponse) Durations() Durations {
+	finished := s.SucceededTime()
+	if finished.IsZero() {
+		finished = s.FailedTime()
+	}
+
+	return Durations{
+		Queue:    between(s.RegisteredTime(), s.AwaitingClockTime()),
+		Wait:     between(s.AwaitingClockTime(), s.SendingHTTPTime()),
+		Delivery: between(s.SendingHTTPTime(), finished),
+	}
+}
+
+func parseTimeOrZero(s string) time.Time {
+	t, _ := ParseTime(s)
+	return t
+}
+
+// between returns the duration from to to, zero when any of them is zero
+func between(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+	return to.Sub(from)
+}
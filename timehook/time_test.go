@@ -0,0 +1,120 @@
+package timehook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timehook/cli-client/timehook"
+)
+
+func TestParseTime(t *testing.T) {
+	tt := []struct {
+		given   string
+		want    string
+		wantErr bool
+	}{
+		{given: "2018-01-29T12:32:25+0000", want: "2018-01-29T12:32:25Z"},
+		{given: "2018-01-29T12:32:25Z", want: "2018-01-29T12:32:25Z"},
+		{given: "2018-01-29T14:32:25+02:00", want: "2018-01-29T12:32:25Z"},
+		{given: "2018-01-29T14:32:25+0200", want: "2018-01-29T12:32:25Z"},
+		{given: "2018-01-29T07:32:25-05", want: "2018-01-29T12:32:25Z"},
+		{given: "2018-01-29T12:32:25.123Z", want: "2018-01-29T12:32:25.123Z"},
+		{given: "2018-01-29T12:32:25.123456+0000", want: "2018-01-29T12:32:25.123456Z"},
+		{given: "20180129T123225Z", want: "2018-01-29T12:32:25Z"},
+		{given: "2018-01-29T12:32:25", want: "2018-01-29T12:32:25Z"},
+		{given: "2018-01-29 12:32:25", wantErr: true},
+		{given: "", wantErr: true},
+	}
+
+	for _, v := range tt {
+		t.Run(v.given, func(t *testing.T) {
+			got, err := timehook.ParseTime(v.given)
+			if v.wantErr {
+				if err == nil {
+					t.Errorf("expected error parsing %q got %s", v.given, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got.UTC().Format(time.RFC3339Nano) != v.want {
+				t.Errorf("wrong time want %s got %s", v.want, got.UTC().Format(time.RFC3339Nano))
+			}
+		})
+	}
+}
+
+func TestParseTimeIn(t *testing.T) {
+	madrid := time.FixedZone("CET", 3600)
+	tt := []struct {
+		given   string
+		want    string
+		wantErr bool
+	}{
+		{given: "2026-11-01T09:00:00+02:00", want: "2026-11-01T07:00:00Z"},
+		{given: "2026-11-01T09:00+02:00", want: "2026-11-01T07:00:00Z"},
+		{given: "2026-11-01T09:00:00Z", want: "2026-11-01T09:00:00Z"},
+		{given: "2026-11-01T09:00:00", want: "2026-11-01T08:00:00Z"},
+		{given: "2026-11-01T09:00", want: "2026-11-01T08:00:00Z"},
+		{given: "2026-11-01 09:00:00", want: "2026-11-01T08:00:00Z"},
+		{given: "2026-11-01 09:00", want: "2026-11-01T08:00:00Z"},
+		{given: "2026-11-01", wantErr: true},
+		{given: "tomorrow", wantErr: true},
+	}
+
+	for _, v := range tt {
+		t.Run(v.given, func(t *testing.T) {
+			got, err := timehook.ParseTimeIn(v.given, madrid)
+			if v.wantErr {
+				if err == nil {
+					t.Errorf("expected error parsing %q got %s", v.given, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got.UTC().Format(time.RFC3339Nano) != v.want {
+				t.Errorf("wrong time want %s got %s", v.want, got.UTC().Format(time.RFC3339Nano))
+			}
+		})
+	}
+}
+
+func TestStateResponse_Durations(t *testing.T) {
+	tt := []struct {
+		name  string
+		given *timehook.StateResponse
+		want  timehook.Durations
+	}{
+		{
+			name:  "succeeded",
+			given: stateSucceeded(),
+			want:  timehook.Durations{Queue: 1 * time.Second, Wait: 29 * time.Second, Delivery: 1 * time.Second},
+		},
+		{
+			name:  "failed",
+			given: stateFailed(),
+			want:  timehook.Durations{Queue: 1 * time.Second, Wait: 29 * time.Second, Delivery: 2 * time.Second},
+		},
+		{
+			name:  "awaiting",
+			given: stateAwaiting(),
+			want:  timehook.Durations{Queue: 1 * time.Second},
+		},
+		{
+			name:  "wrong date",
+			given: stateSucceededWrongDate(),
+			want:  timehook.Durations{Queue: 1 * time.Second, Wait: 29 * time.Second},
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			if got := v.given.Durations(); got != v.want {
+				t.Errorf("wrong durations want %+v got %+v", v.want, got)
+			}
+		})
+	}
+}